@@ -0,0 +1,129 @@
+package goini
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupMode selects how an existing file is backed up when a configuration is saved.
+type BackupMode int
+
+const (
+	// BackupSingle keeps a single backup next to the file, with a .bak extension.
+	BackupSingle BackupMode = iota
+	// BackupNone makes no backup.
+	BackupNone
+	// BackupNumbered keeps up to BackupPolicy.Count backups next to the file with
+	// .1, .2 … extensions, .1 being the most recent.
+	BackupNumbered
+	// BackupDir keeps backups in BackupPolicy.Dir, named after the file with a
+	// timestamp extension. Only the BackupPolicy.Count most recent are kept if Count
+	// is not zero.
+	BackupDir
+)
+
+// defaultBackupCount is the number of numbered backups kept when BackupPolicy.Count is zero.
+const defaultBackupCount = 5
+
+// BackupPolicy controls the backup made of an existing file when saving a configuration.
+type BackupPolicy struct {
+	Mode  BackupMode
+	Count int    // number of backups kept, see BackupNumbered and BackupDir
+	Dir   string // directory of the backups with BackupDir
+}
+
+// backupTimeFormat is the timestamp extension of the backups made with BackupDir.
+// It sorts in chronological order.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// BackupPath returns the path of the backup made by the last Save or SaveAs, or an
+// empty string if no backup was made.
+func (c *IniFile) BackupPath() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.backupPath
+}
+
+// backup makes a backup of filePath according to the policy and returns its path.
+func (p BackupPolicy) backup(filePath string) (string, error) {
+	switch p.Mode {
+	case BackupNone:
+		return "", nil
+	case BackupNumbered:
+		count := p.Count
+		if count <= 0 {
+			count = defaultBackupCount
+		}
+		for i := count - 1; i >= 1; i-- {
+			err := os.Rename(filePath+"."+strconv.Itoa(i), filePath+"."+strconv.Itoa(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+		backup := filePath + ".1"
+		return backup, backupFile(filePath, backup)
+	case BackupDir:
+		if err := os.MkdirAll(p.Dir, 0700); err != nil {
+			return "", err
+		}
+		name := filepath.Base(filePath)
+		backup := filepath.Join(p.Dir, name+"."+time.Now().Format(backupTimeFormat))
+		if err := backupFile(filePath, backup); err != nil {
+			return "", err
+		}
+		return backup, p.prune(name)
+	}
+	backup := filePath + ".bak"
+	return backup, backupFile(filePath, backup)
+}
+
+// prune removes the oldest timestamped backups of the file name from the backup
+// directory, keeping Count of them. Nothing is removed if Count is zero.
+func (p BackupPolicy) prune(name string) error {
+	if p.Count <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(p.Dir, name+".*"))
+	if err != nil {
+		return err
+	}
+	backups := matches[:0]
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(filepath.Base(match), name+".")
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	slices.Sort(backups)
+	for len(backups) > p.Count {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backupFile makes backup a copy of filePath, as a hard link if possible.
+func backupFile(filePath, backup string) error {
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(filePath, backup) == nil {
+		return nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(backup, data, info.Mode().Perm())
+}
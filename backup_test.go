@@ -0,0 +1,91 @@
+package goini
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// saveVersions saves the configuration at filePath n times, setting k to the
+// number of the save, and returns the configuration.
+func saveVersions(t *testing.T, filePath string, policy BackupPolicy, n int) *IniFile {
+	t.Helper()
+	c, err := ParseString("[s]\nk = 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetFilePath(filePath)
+	c.SetSaveOptions(SaveOptions{Backup: policy})
+	for i := 1; i <= n; i++ {
+		c.section("s").Add("k", strconv.Itoa(i))
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestBackupSingle(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "app.ini")
+	c := saveVersions(t, filePath, BackupPolicy{}, 1)
+	if got := c.BackupPath(); got != "" {
+		t.Errorf("backup path is %q, want none for a new file", got)
+	}
+	c = saveVersions(t, filePath, BackupPolicy{}, 2)
+	if got := c.BackupPath(); got != filePath+".bak" {
+		t.Errorf("backup path is %q, want %q", got, filePath+".bak")
+	}
+	if got := readFile(t, filePath+".bak"); got != "[s]\nk=1\n" {
+		t.Errorf("backup holds %q, want the previous content", got)
+	}
+	if got := readFile(t, filePath); got != "[s]\nk=2\n" {
+		t.Errorf("file holds %q, want the last content", got)
+	}
+}
+
+func TestBackupNone(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "app.ini")
+	c := saveVersions(t, filePath, BackupPolicy{Mode: BackupNone}, 2)
+	if got := c.BackupPath(); got != "" {
+		t.Errorf("backup path is %q, want none", got)
+	}
+	if _, err := os.Stat(filePath + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup file exists: %v", err)
+	}
+}
+
+func TestBackupNumbered(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "app.ini")
+	c := saveVersions(t, filePath, BackupPolicy{Mode: BackupNumbered, Count: 2}, 4)
+	if got := c.BackupPath(); got != filePath+".1" {
+		t.Errorf("backup path is %q, want %q", got, filePath+".1")
+	}
+	for i, want := range []string{"[s]\nk=3\n", "[s]\nk=2\n"} {
+		if got := readFile(t, filePath+"."+strconv.Itoa(i+1)); got != want {
+			t.Errorf("backup %d holds %q, want %q", i+1, got, want)
+		}
+	}
+	if _, err := os.Stat(filePath + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond the count exists: %v", err)
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	filePath := filepath.Join(t.TempDir(), "app.ini")
+	c := saveVersions(t, filePath, BackupPolicy{Mode: BackupDir, Dir: dir, Count: 2}, 4)
+	if got := filepath.Dir(c.BackupPath()); got != dir {
+		t.Errorf("backup path is %q, want a file in %q", c.BackupPath(), dir)
+	}
+	if got := readFile(t, c.BackupPath()); got != "[s]\nk=3\n" {
+		t.Errorf("last backup holds %q, want the previous content", got)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d backups kept, want 2", len(entries))
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package goini
+
+import (
+	"os"
+	"syscall"
+)
+
+// chownLike gives f the owner and group of the file described by info where permitted.
+func chownLike(f *os.File, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		f.Chown(int(st.Uid), int(st.Gid)) // not permitted unless privileged, keep the defaults
+	}
+}
@@ -0,0 +1,197 @@
+// Package convert converts goini configurations to and from YAML, read with
+// gopkg.in/yaml.v3, and TOML, read with github.com/BurntSushi/toml.
+//
+// Options of the global section are written at the top level and sections as
+// mappings, or tables, of options named by the text of their header, such as
+// `remote "origin"`. Options with shadow values are written as sequences of strings.
+//
+// When reading, top-level scalars and sequences of scalars go to the global section,
+// and mappings become sections. Mappings nested in a section become sections named
+// by the dotted path of their keys, `db: {pool: {max: 5}}` giving a [db.pool] section,
+// and sequences of mappings, such as TOML arrays of tables, become as many sections
+// of the same name. Scalars are read as their text, null as an empty string.
+package convert
+
+import (
+	"fmt"
+
+	"github.com/sambios/goini"
+)
+
+// option is an option with all its values.
+type option struct {
+	name   string
+	values []string
+}
+
+// section is a section with its options in order.
+type section struct {
+	key     string // header text without brackets
+	global  bool
+	options []option
+}
+
+// sections returns the sections of c in order, merging sections declared several
+// times, the global section first if it has options. It fails if an option of the
+// global section has the name of a section, as both are keys of the same mapping.
+func sections(c *goini.IniFile) ([]*section, error) {
+	var result []*section
+	var global *section
+	byKey := make(map[string]*section)
+	for _, s := range c.Sections() {
+		key := s.HeaderText()
+		sec := byKey[key]
+		if s.IsGlobal() {
+			sec = global
+		}
+		if sec == nil {
+			sec = &section{key: key, global: s.IsGlobal()}
+			if sec.global {
+				global = sec
+			} else {
+				byKey[key] = sec
+			}
+			result = append(result, sec)
+		}
+		for _, name := range s.OptionNames() {
+			values := s.ValuesOf(name)
+			replaced := false
+			for i := range sec.options {
+				if sec.options[i].name == name {
+					sec.options[i].values, replaced = values, true
+				}
+			}
+			if !replaced {
+				sec.options = append(sec.options, option{name, values})
+			}
+		}
+	}
+	if global == nil {
+		return result, nil
+	}
+	for _, opt := range global.options {
+		if byKey[opt.name] != nil {
+			return nil, fmt.Errorf("option %s of the global section has the name of a section", opt.name)
+		}
+	}
+	sorted := []*section{global}
+	for _, sec := range result {
+		if sec != global {
+			sorted = append(sorted, sec)
+		}
+	}
+	if len(global.options) == 0 {
+		sorted = sorted[1:]
+	}
+	return sorted, nil
+}
+
+// entry is a key of a decoded mapping with its value: a string, a []string for a
+// sequence of scalars, an []entry for a mapping or an [][]entry for a sequence of
+// mappings.
+type entry struct {
+	key   string
+	value any
+}
+
+// setEntry sets the value of e.key in entries, replacing it in place if it is set.
+func setEntry(entries []entry, e entry) []entry {
+	for i := range entries {
+		if entries[i].key == e.key {
+			entries[i].value = e.value
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+// build returns a configuration holding the entries of a decoded document.
+func build(doc []entry) *goini.IniFile {
+	c := goini.NewIniFile("")
+	global := c.AddSection(c.GlobalSectionName())
+	for _, e := range doc {
+		if !setOption(global, e) {
+			buildSection(c, e.key, e.value)
+		}
+	}
+	return c
+}
+
+// buildSection adds the section name from a mapping, or a section per mapping of a
+// sequence, the mappings they contain becoming sections named name.key.
+func buildSection(c *goini.IniFile, name string, value any) {
+	switch v := value.(type) {
+	case []entry:
+		s := addSection(c, name)
+		for _, e := range v {
+			if !setOption(s, e) {
+				buildSection(c, name+"."+e.key, e.value)
+			}
+		}
+	case [][]entry:
+		for _, m := range v {
+			buildSection(c, name, m)
+		}
+	}
+}
+
+// setOption adds the option of e to s if its value is a scalar or a sequence of
+// scalars, and returns whether it did.
+func setOption(s *goini.Section, e entry) bool {
+	switch v := e.value.(type) {
+	case string:
+		s.Add(e.key, v)
+	case []string:
+		for i, value := range v {
+			if i == 0 {
+				s.Add(e.key, value)
+			} else {
+				s.AddShadow(e.key, value)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// addSection adds the section whose header text is key to c, see goini.ParseHeaderText.
+func addSection(c *goini.IniFile, key string) *goini.Section {
+	name, subName, parent := goini.ParseHeaderText(key)
+	s := c.AddSubsection(name, subName)
+	if parent != "" {
+		s.SetParent(parent)
+	}
+	return s
+}
+
+// sequence returns the value of a decoded sequence from its elements, each a string
+// or an []entry: a []string or an [][]entry.
+func sequence(values []any) (any, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("empty sequence")
+	}
+	switch values[0].(type) {
+	case string:
+		result := make([]string, len(values))
+		for i, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("sequence mixing scalars and other values")
+			}
+			result[i] = s
+		}
+		return result, nil
+	case []entry:
+		result := make([][]entry, len(values))
+		for i, v := range values {
+			m, ok := v.([]entry)
+			if !ok {
+				return nil, fmt.Errorf("sequence mixing mappings and other values")
+			}
+			result[i] = m
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("nested sequences are not supported")
+}
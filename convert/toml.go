@@ -0,0 +1,195 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sambios/goini"
+)
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey returns key as a TOML key, quoted unless it is a bare key.
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ToTOML returns the configuration as a TOML document with a table per section.
+// Options of the global section are written first, at the top level. Section names
+// containing dots are quoted so they are not read as nested tables by other tools.
+// Values are written as strings, or arrays of strings for options with shadow values.
+func ToTOML(c *goini.IniFile) ([]byte, error) {
+	all, err := sections(c)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, sec := range all {
+		if !sec.global {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString("[" + tomlKey(sec.key) + "]\n")
+		}
+		for _, opt := range sec.options {
+			b.WriteString(tomlKey(opt.name) + " = ")
+			if len(opt.values) == 1 {
+				b.WriteString(tomlString(opt.values[0]) + "\n")
+				continue
+			}
+			quoted := make([]string, len(opt.values))
+			for i, value := range opt.values {
+				quoted[i] = tomlString(value)
+			}
+			b.WriteString("[" + strings.Join(quoted, ", ") + "]\n")
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// FromTOML builds a configuration from a TOML document, see the package documentation.
+// Keys keep their order in the document. Dates and times are read in RFC 3339 format.
+func FromTOML(data []byte) (*goini.IniFile, error) {
+	var doc map[string]any
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[string]int)
+	for i, key := range md.Keys() {
+		if _, ok := order[tomlPath(key)]; !ok {
+			order[tomlPath(key)] = i
+		}
+	}
+	entries, err := tomlTable(doc, nil, order)
+	if err != nil {
+		return nil, err
+	}
+	return build(entries), nil
+}
+
+// tomlPath identifies the key at path in a document.
+func tomlPath(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// tomlTable returns the entries of the table at path, in the order of their keys in
+// the document.
+func tomlTable(table map[string]any, path []string, order map[string]int) ([]entry, error) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return order[tomlPath(append(slices.Clip(path), a))] - order[tomlPath(append(slices.Clip(path), b))]
+	})
+
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		keyPath := append(slices.Clip(path), key)
+		value, err := tomlValue(table[key], keyPath, order)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry{key, value}
+	}
+	return entries, nil
+}
+
+// tomlValue returns a decoded value as held by an entry.
+func tomlValue(value any, path []string, order map[string]int) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return tomlTable(v, path, order)
+	case []map[string]any:
+		tables := make([][]entry, len(v))
+		for i, table := range v {
+			entries, err := tomlTable(table, path, order)
+			if err != nil {
+				return nil, err
+			}
+			tables[i] = entries
+		}
+		return tables, nil
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			value, err := tomlValue(item, path, order)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		seq, err := sequence(values)
+		if err != nil {
+			return nil, fmt.Errorf("toml key %s: %v", strings.Join(path, "."), err)
+		}
+		return seq, nil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return tomlTime(v), nil
+	}
+	return nil, fmt.Errorf("toml key %s: unsupported value %v", strings.Join(path, "."), value)
+}
+
+// tomlTime returns the text of a date and time, without offset for the local dates
+// and times of TOML, which the decoder sets in locations of these names.
+func tomlTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format(time.DateOnly)
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,172 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sambios/goini"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlString returns a node for the string s, quoted when written if it would not
+// be read back as a string, by YAML 1.1 readers too.
+func yamlString(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	switch strings.ToLower(s) {
+	case "yes", "no", "on", "off", "y", "n":
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+// yamlValues returns a node for the values of an option: a string, or a sequence of
+// strings for an option with shadow values.
+func yamlValues(values []string) *yaml.Node {
+	if len(values) == 1 {
+		return yamlString(values[0])
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, value := range values {
+		seq.Content = append(seq.Content, yamlString(value))
+	}
+	return seq
+}
+
+// ToYAML returns the configuration as a YAML mapping with the options of the global
+// section followed by a mapping of options per section.
+func ToYAML(c *goini.IniFile) ([]byte, error) {
+	all, err := sections(c)
+	if err != nil {
+		return nil, err
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, sec := range all {
+		if sec.global {
+			for _, opt := range sec.options {
+				root.Content = append(root.Content, yamlString(opt.name), yamlValues(opt.values))
+			}
+			continue
+		}
+		options := &yaml.Node{Kind: yaml.MappingNode}
+		for _, opt := range sec.options {
+			options.Content = append(options.Content, yamlString(opt.name), yamlValues(opt.values))
+		}
+		root.Content = append(root.Content, yamlString(sec.key), options)
+	}
+
+	var b bytes.Buffer
+	if len(root.Content) == 0 {
+		return b.Bytes(), nil
+	}
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// FromYAML builds a configuration from a YAML mapping, see the package documentation.
+// Anchors, aliases and merge keys are resolved.
+func FromYAML(data []byte) (*goini.IniFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return build(nil), nil
+	}
+	root := yamlResolve(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("yaml line %d: the document must be a mapping", root.Line)
+	}
+	entries, err := yamlMapping(root)
+	if err != nil {
+		return nil, err
+	}
+	return build(entries), nil
+}
+
+// yamlResolve returns the node an alias refers to, or n.
+func yamlResolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// yamlMapping returns the entries of a mapping node. The mappings merged with "<<"
+// keys are applied first, so that the keys of the mapping itself override theirs.
+func yamlMapping(n *yaml.Node) ([]entry, error) {
+	var entries []entry
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], yamlResolve(n.Content[i+1])
+		if key.Tag != "!!merge" {
+			continue
+		}
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, m := range merged {
+			if m = yamlResolve(m); m.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("yaml line %d: merge of a value other than a mapping", m.Line)
+			}
+			mapping, err := yamlMapping(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range mapping {
+				entries = setEntry(entries, e)
+			}
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag == "!!merge" {
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("yaml line %d: keys must be scalars", key.Line)
+		}
+		v, err := yamlValue(value)
+		if err != nil {
+			return nil, err
+		}
+		entries = setEntry(entries, entry{key.Value, v})
+	}
+	return entries, nil
+}
+
+// yamlValue returns the value of a node as held by an entry.
+func yamlValue(n *yaml.Node) (any, error) {
+	n = yamlResolve(n)
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+	case yaml.MappingNode:
+		return yamlMapping(n)
+	case yaml.SequenceNode:
+		values := make([]any, len(n.Content))
+		for i, item := range n.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		v, err := sequence(values)
+		if err != nil {
+			return nil, fmt.Errorf("yaml line %d: %v", n.Line, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("yaml line %d: unsupported value", n.Line)
+}
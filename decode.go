@@ -0,0 +1,458 @@
+package goini
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decoders holds the functions registered with RegisterDecoder, by type.
+var (
+	decodersMutex sync.RWMutex
+	decoders      = map[reflect.Type]func(string) (any, error){}
+)
+
+// RegisterDecoder makes Decode convert the values of fields of type t with fn,
+// which must return a value assignable to t. Decoders take precedence over the
+// encoding.TextUnmarshaler implementation of t, if any. Encode writes values of t
+// with their encoding.TextMarshaler implementation, or fmt.Sprint otherwise:
+//
+//	goini.RegisterDecoder(reflect.TypeOf(slog.LevelInfo), func(s string) (any, error) {
+//		var level slog.Level
+//		return level, level.UnmarshalText([]byte(s))
+//	})
+func RegisterDecoder(t reflect.Type, fn func(string) (any, error)) {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+
+	decoders[t] = fn
+}
+
+// decoderOf returns the decoder registered for t, or nil.
+func decoderOf(t reflect.Type) func(string) (any, error) {
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+
+	return decoders[t]
+}
+
+// isTextType returns true if values of type t are decoded from text by a registered
+// decoder or their encoding.TextUnmarshaler implementation.
+func isTextType(t reflect.Type) bool {
+	return decoderOf(t) != nil || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// fieldName returns the option or section name for a struct field and whether the field must be skipped.
+func fieldName(f reflect.StructField) (name string, skip bool) {
+	if !f.IsExported() && (!f.Anonymous || f.Type.Kind() != reflect.Struct) { // only embedded structs are walked
+		return "", true
+	}
+	tag := f.Tag.Get("ini")
+	if tag == "-" {
+		return "", true
+	}
+	if i := strings.Index(tag, ","); i != -1 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		tag = f.Name
+	}
+	return tag, false
+}
+
+// isNameField returns true if the field is tagged `ini:",name"` to receive the name
+// of the section an element of a slice of sections is decoded from.
+func isNameField(f reflect.StructField) bool {
+	_, opts, _ := strings.Cut(f.Tag.Get("ini"), ",")
+	return opts == "name"
+}
+
+// isSectionSliceField returns true if the field is a slice of structs, or of pointers
+// to structs, mapped to repeated sections.
+func isSectionSliceField(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && isSectionField(t.Elem())
+}
+
+// isSectionMapField returns true if the field is a map with string keys mapped to
+// a whole section.
+func isSectionMapField(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
+
+// isSectionField returns true if the field is mapped to a section rather than an option.
+func isSectionField(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isTextType(t)
+}
+
+// Decode populates the struct pointed to by v with the values of the configuration.
+// Struct fields are mapped to sections and their fields to options, other fields
+// of v are read from the global section. Struct fields of a section struct are mapped
+// to the dotted subsections "section.field". The name used for a field is taken
+// from its `ini:"name"` tag or defaults to the field name; `ini:"-"` skips the field.
+// Supported field types are strings, bools, integers, floats, time.Duration and slices
+// of those, the latter being read as comma separated lists whose elements may be
+// enclosed in double quotes to contain commas, as well as the types
+// implementing encoding.TextUnmarshaler and those registered with RegisterDecoder.
+//
+// Slices of structs are mapped to repeated sections, see decodeSections, and maps
+// with string keys to all the options of a section.
+//
+// Fields tagged `default:"value"` are set to value when their option is missing.
+// Missing options of fields tagged `required:"true"` fail the decoding with an
+// error joining a Violation for each of them.
+func (c *IniFile) Decode(v interface{}) error {
+	return DecodeOptions{}.Decode(c, v)
+}
+
+// DecodeOptions controls how a configuration is decoded into a struct.
+type DecodeOptions struct {
+	// DisallowUnknownKeys fails the decoding if the configuration has options or
+	// sections that no field is mapped to, such as misspelled ones. The error joins
+	// a Violation for each of them.
+	DisallowUnknownKeys bool
+}
+
+// Decode populates the struct pointed to by v with the values of c, see IniFile.Decode.
+func (o DecodeOptions) Decode(c *IniFile, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Decode requires a non-nil pointer to a struct")
+	}
+	d := &decoder{c: c, known: make(map[string]map[string]bool)}
+	global := c.globalSection()
+	if err := d.decodeFields(c.GlobalSectionName(), "", global, rv.Elem()); err != nil {
+		return err
+	}
+	if o.DisallowUnknownKeys {
+		d.violations = append(d.violations, c.unknownKeys(d.known)...)
+	}
+	var errs []error
+	for _, v := range d.violations {
+		errs = append(errs, v)
+	}
+	return errors.Join(errs...)
+}
+
+// decoder holds the state of a single decoding.
+type decoder struct {
+	c          *IniFile
+	known      map[string]map[string]bool // options fields are mapped to, by section name
+	violations []Violation                // missing required options and unknown options
+}
+
+// mapped records that a field is mapped to option in the section name.
+func (d *decoder) mapped(name, option string) {
+	if d.known[name] == nil {
+		d.known[name] = make(map[string]bool)
+	}
+	if option != "" {
+		d.known[name][option] = true
+	}
+}
+
+// subsectionName returns the name of the subsection child of the section parent.
+func subsectionName(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+// decodeSection populates the struct, or pointer to struct, fv with the section name.
+// If neither the section nor any of its subsections exist, pointers are left nil and
+// structs only get their default values.
+func (d *decoder) decodeSection(name string, fv reflect.Value) error {
+	section, err := d.c.Section(name)
+	if err != nil && !d.c.hasSubsections(name) && fv.Kind() == reflect.Ptr {
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	return d.decodeFields(name, name, section, fv)
+}
+
+// decodeSections populates the slice of structs, or pointers to structs, fv with an
+// element per section named name, such as `[name "a"]`, and per section one level
+// below it, such as "[name.a]", in order. The field of the element tagged
+// `ini:",name"`, if any, is set to the subsection name, "a" in both examples.
+func (d *decoder) decodeSections(name string, fv reflect.Value) error {
+	slice := reflect.MakeSlice(fv.Type(), 0, 0)
+	for _, s := range d.c.Sections() {
+		sectionName, elemName := s.Name(), s.SubName()
+		if sectionName != name {
+			child, ok := strings.CutPrefix(sectionName, name+".")
+			if !ok || child == "" || strings.Contains(child, ".") {
+				continue
+			}
+			elemName = child
+		}
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		ev := elem
+		if ev.Kind() == reflect.Ptr {
+			ev.Set(reflect.New(ev.Type().Elem()))
+			ev = ev.Elem()
+		}
+		if err := d.decodeFields(sectionName, sectionName, s, ev); err != nil {
+			return err
+		}
+		for i := 0; i < ev.NumField(); i++ {
+			if f := ev.Type().Field(i); isNameField(f) && f.Type.Kind() == reflect.String {
+				ev.Field(i).SetString(elemName)
+			}
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// decodeMap populates the map fv with every option of the section name, converted
+// to the element type of the map. The map is left unchanged if the section does not exist.
+func (d *decoder) decodeMap(name string, fv reflect.Value) error {
+	section := d.c.section(name)
+	if section == nil {
+		return nil
+	}
+	d.mapped(name, "")
+	m := reflect.MakeMap(fv.Type())
+	for _, opt := range section.OptionNames() {
+		d.mapped(name, opt)
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if _, err := decodeOption(section, opt, elem); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(opt).Convert(fv.Type().Key()), elem)
+	}
+	fv.Set(m)
+	return nil
+}
+
+// decodeFields populates the fields of the struct rv with the options of section,
+// named sectionName and which may be nil, and with the subsections of the section
+// name, empty for the global section.
+func (d *decoder) decodeFields(sectionName, name string, section *Section, rv reflect.Value) error {
+	d.mapped(sectionName, "")
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		fieldName, skip := fieldName(f)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := d.decodeFields(sectionName, name, section, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isNameField(f) {
+			continue
+		}
+		if isSectionSliceField(f.Type) {
+			if err := d.decodeSections(subsectionName(name, fieldName), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isSectionMapField(f.Type) {
+			if err := d.decodeMap(subsectionName(name, fieldName), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isSectionField(f.Type) {
+			if err := d.decodeSection(subsectionName(name, fieldName), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		d.mapped(sectionName, fieldName)
+		found := false
+		if section != nil {
+			var err error
+			if found, err = decodeOption(section, fieldName, fv); err != nil {
+				return err
+			}
+		}
+		if !found {
+			if err := d.missing(sectionName, section, fieldName, f, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeOption sets fv from the value of option if the option exists in section,
+// and returns whether it exists.
+func decodeOption(section *Section, option string, fv reflect.Value) (bool, error) {
+	value, err := section.value(option)
+	if err != nil {
+		return false, nil
+	}
+	if err := setValue(fv, value); err != nil {
+		if pos := section.PositionOf(option); pos.IsValid() {
+			return true, fmt.Errorf("%s: invalid value for %s in section %s: %v", pos, option, section.Name(), err)
+		}
+		return true, fmt.Errorf("invalid value for %s in section %s: %v", option, section.Name(), err)
+	}
+	return true, nil
+}
+
+// missing handles the option missing in section, which may be nil, the field f
+// being mapped to: fv is set from its `default:"value"` tag if any, otherwise the
+// option is recorded as missing if the field has a `required:"true"` tag.
+func (d *decoder) missing(sectionName string, section *Section, option string, f reflect.StructField, fv reflect.Value) error {
+	if def, ok := f.Tag.Lookup("default"); ok {
+		if err := setValue(fv, def); err != nil {
+			return fmt.Errorf("invalid default value for %s in section %s: %v", option, sectionName, err)
+		}
+		return nil
+	}
+	if required, _ := strconv.ParseBool(f.Tag.Get("required")); required {
+		v := Violation{Section: sectionName, Option: option, Msg: "missing option"}
+		if section != nil {
+			v.Position = section.Position()
+		}
+		d.violations = append(d.violations, v)
+	}
+	return nil
+}
+
+// setValue converts value to the type of fv and stores it.
+func setValue(fv reflect.Value, value string) error {
+	if fn := decoderOf(fv.Type()); fn != nil {
+		v, err := fn(value)
+		if err != nil {
+			return err
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().AssignableTo(fv.Type()) {
+			return fmt.Errorf("decoder of %s returned %T", fv.Type(), v)
+		}
+		fv.Set(rv)
+		return nil
+	}
+	if fv.Kind() != reflect.Ptr && fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		var parts []string
+		if strings.TrimSpace(value) != "" {
+			parts = splitList(value)
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setValue(fv.Elem(), value)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// splitList splits a list value written by formatValue on its commas. Elements in
+// double quotes may contain commas and are unquoted, the others are trimmed.
+func splitList(value string) []string {
+	var parts []string
+	for {
+		value = strings.TrimLeft(value, " \t")
+		if end := closingQuote(value); end != -1 {
+			rest := strings.TrimLeft(value[end+1:], " \t")
+			if part, err := strconv.Unquote(value[:end+1]); err == nil && (rest == "" || rest[0] == ',') {
+				parts = append(parts, part)
+				if rest == "" {
+					return parts
+				}
+				value = rest[1:]
+				continue
+			}
+		}
+		part, rest, found := strings.Cut(value, ",")
+		parts = append(parts, strings.TrimSpace(part))
+		if !found {
+			return parts
+		}
+		value = rest
+	}
+}
+
+// closingQuote returns the index of the double quote closing the one starting s, or -1.
+func closingQuote(s string) int {
+	if s == "" || s[0] != '"' {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,298 @@
+package goini
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Dialect selects the syntax of the configuration files being parsed and written.
+type Dialect int
+
+const (
+	// DialectINI is the INI syntax with sections and "key = value" options.
+	DialectINI Dialect = iota
+	// DialectProperties is the syntax of Java .properties files: flat "key=value",
+	// "key:value" or "key value" lines without sections, with backslash and \uXXXX
+	// escapes, backslash line continuations and '#' or '!' comments. All the
+	// properties are stored in the global section.
+	DialectProperties
+	// DialectSystemd is the syntax of systemd unit files: options are "key=value"
+	// lines whose values are neither unquoted nor quoted, an option assigned several
+	// times keeps every value, see Section.ValuesOf, and a line ending with a backslash
+	// is joined with the following one by a space. An empty assignment resets the
+	// values assigned so far, the values assigned next replacing the empty value; it
+	// is written back before them so that drop-ins resetting a list keep doing so.
+	DialectSystemd
+)
+
+// Dialect returns the dialect used when writing the configuration.
+func (c *IniFile) Dialect() Dialect {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.dialect
+}
+
+// SetDialect sets the dialect used when writing the configuration. When writing
+// the properties dialect, the options of sections other than the global one are
+// written with the section name as a dotted prefix and inline comments are omitted.
+func (c *IniFile) SetDialect(dialect Dialect) {
+	if c.Frozen() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.dialect = dialect
+	c.restructure()
+}
+
+// formatSection returns the text representation of s in the dialect of the configuration.
+func (c *IniFile) formatSection(s *Section) string {
+	switch {
+	case c.dialect == DialectProperties:
+		return s.formatProperties()
+	case s.raw != nil:
+		return s.formatRaw(c.writeOptions(), c.dialect)
+	}
+	return s.format(c.writeOptions(), c.dialect)
+}
+
+//
+// systemd
+//
+
+// parseSystemdOption parses a "key=value" line of a systemd unit file into the active section.
+func (p *parser) parseSystemdOption(line string) error {
+	i := strings.IndexByte(line, '=')
+	if i == -1 {
+		if p.opts.Strict {
+			return p.errorf(line, "missing delimiter")
+		}
+		return nil
+	}
+	key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	if key == "" && p.opts.Strict {
+		return p.errorf(line, "missing option name")
+	}
+	if value != "" && p.active.ValueOfRaw(key) != "" {
+		p.active.AddShadow(key, value)
+	} else {
+		p.forgetOption(key) // reset by this line or following a reset
+		p.active.Add(key, value)
+	}
+	if value == "" {
+		p.active.mutex.Lock()
+		if p.active.resets == nil {
+			p.active.resets = make(map[string]bool)
+		}
+		p.active.resets[key] = true
+		p.active.mutex.Unlock()
+	}
+	p.recordOption(key)
+	if len(p.comments) > 0 {
+		comment := p.takeComment()
+		if previous := p.active.CommentFor(key); previous != "" {
+			comment = previous + "\n" + comment
+		}
+		p.active.SetCommentFor(key, comment)
+	}
+	return nil
+}
+
+//
+// Properties
+//
+
+// isPropertiesComment returns true if line, without its leading whitespace, is a properties comment.
+func isPropertiesComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!")
+}
+
+// continuesProperty returns true if line ends with an odd number of backslashes,
+// the last one escaping the line break.
+func continuesProperty(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1 && !isPropertiesComment(strings.TrimLeft(line, " \t\f"))
+}
+
+// parsePropertiesLine parses a line of a .properties file into the global section.
+func (p *parser) parsePropertiesLine(line string) error {
+	text := strings.TrimLeft(line, " \t\f")
+	if text == "" {
+		return nil
+	}
+	if isPropertiesComment(text) {
+		p.comments = append(p.comments, text)
+		return nil
+	}
+
+	key, value := splitProperty(text)
+	if p.opts.Strict && key == "" {
+		return p.errorf(line, "missing option name")
+	}
+	if p.active.Exists(key) {
+		switch {
+		case p.opts.Strict || p.opts.DuplicateKeyPolicy == ErrorOnDuplicate:
+			return p.errorf(line, "duplicate option %s", key)
+		case p.opts.DuplicateKeyPolicy == FirstWins:
+			return nil
+		case p.opts.DuplicateKeyPolicy == Append:
+			p.active.AddShadow(key, value)
+			return nil
+		}
+	}
+	p.active.Add(key, value)
+	p.setPosition(key)
+	if len(p.comments) > 0 {
+		// kept as is, SetCommentFor would not recognize '!' comments
+		if p.active.comments == nil {
+			p.active.comments = make(map[string]string)
+		}
+		p.active.comments[key] = p.takeComment()
+	}
+	return nil
+}
+
+// splitProperty splits a properties line into its unescaped key and value. The key ends
+// at the first unescaped '=', ':' or whitespace, which may be surrounded by whitespace.
+func splitProperty(text string) (key, value string) {
+	i := 0
+	for ; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", text[i]) != -1 {
+			break
+		}
+	}
+	if i > len(text) {
+		i = len(text)
+	}
+	key, value = text[:i], strings.TrimLeft(text[i:], " \t\f")
+	if value != "" && (value[0] == '=' || value[0] == ':') {
+		value = strings.TrimLeft(value[1:], " \t\f")
+	}
+	return unescapeProperty(key), unescapeProperty(value)
+}
+
+// unescapeProperty replaces the escape sequences of a properties key or value.
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	var high rune // pending high surrogate of a \uXXXX pair
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		var r rune
+		switch s[i] {
+		case 't':
+			r = '\t'
+		case 'n':
+			r = '\n'
+		case 'r':
+			r = '\r'
+		case 'f':
+			r = '\f'
+		case 'u':
+			n, err := strconv.ParseUint(s[i+1:min(i+5, len(s))], 16, 16)
+			if err != nil || i+5 > len(s) {
+				b.WriteString("\\u")
+				continue
+			}
+			i += 4
+			r = rune(n)
+			if utf16.IsSurrogate(r) {
+				if high != 0 {
+					b.WriteRune(utf16.DecodeRune(high, r))
+					high = 0
+				} else {
+					high = r
+				}
+				continue
+			}
+		default:
+			r = rune(s[i])
+		}
+		if high != 0 {
+			b.WriteRune(high)
+			high = 0
+		}
+		b.WriteRune(r)
+	}
+	if high != 0 {
+		b.WriteRune(high)
+	}
+	return b.String()
+}
+
+// escapeProperty returns s escaped for use as a properties key, if key is true, or value.
+// Characters outside of printable ASCII are written as \uXXXX escapes.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0),
+			(r == '=' || r == ':') && key,
+			(r == '#' || r == '!') && i == 0:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				b.WriteString(`\u` + strings.ToUpper(strconv.FormatUint(uint64(u)|0x10000, 16)[1:]))
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatProperties returns the options of the section as properties lines.
+func (s *Section) formatProperties() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var prefix string
+	if !s.global {
+		prefix = s.name + "."
+		if s.subName != "" {
+			prefix += s.subName + "."
+		}
+	}
+	var b strings.Builder
+	if s.comment != "" {
+		b.WriteString(s.comment + "\n")
+	}
+	for _, opt := range s.orderedOptions {
+		if comment, ok := s.comments[opt]; ok {
+			b.WriteString(comment + "\n")
+		}
+		key := escapeProperty(prefix+opt, true)
+		b.WriteString(key + "=" + escapeProperty(s.options[opt], false) + "\n")
+		for _, value := range s.shadows[opt] {
+			b.WriteString(key + "=" + escapeProperty(value, false) + "\n")
+		}
+	}
+	if s.trailingComment != "" {
+		b.WriteString(s.trailingComment + "\n")
+	}
+	return b.String()
+}
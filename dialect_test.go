@@ -0,0 +1,92 @@
+package goini
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPropertiesDialect(t *testing.T) {
+	opts := ParseOptions{Dialect: DialectProperties}
+	c, err := opts.ParseString("# comment\n! other comment\n" +
+		"equals=1\ncolon : 2\nspace   3\n" +
+		"escaped\\ key\\=x = a\\tb\n" +
+		"unicode = caf\\u00e9 \\uD83D\\uDE00\n" +
+		"continued = one, \\\n    two\n" +
+		"empty\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := c.section(c.GlobalSectionName())
+	tests := map[string]string{
+		"equals":        "1",
+		"colon":         "2",
+		"space":         "3",
+		"escaped key=x": "a\tb",
+		"unicode":       "café 😀",
+		"continued":     "one, two",
+		"empty":         "",
+	}
+	for key, want := range tests {
+		if got, ok := global.ValueOk(key); !ok || got != want {
+			t.Errorf("%q is %q, %v, want %q", key, got, ok, want)
+		}
+	}
+	if got := global.CommentFor("equals"); got != "# comment\n! other comment" {
+		t.Errorf("comment of equals is %q", got)
+	}
+
+	again := roundTrip(t, c, opts).section(c.GlobalSectionName())
+	for key, want := range tests {
+		if got := again.ValueOf(key); got != want {
+			t.Errorf("%q read back is %q, want %q", key, got, want)
+		}
+	}
+	if got := again.CommentFor("equals"); got != "# comment\n! other comment" {
+		t.Errorf("comment of equals read back is %q", got)
+	}
+}
+
+func TestPropertiesDialectSections(t *testing.T) {
+	c, err := ParseString("top = 1\n[db]\nhost = h\n[remote \"origin\"]\nurl = u\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDialect(DialectProperties)
+	if got, want := c.String(), "top=1\ndb.host=h\nremote.origin.url=u\n"; got != want {
+		t.Errorf("written properties are %q, want %q", got, want)
+	}
+}
+
+func TestSystemdDialect(t *testing.T) {
+	opts := ParseOptions{Dialect: DialectSystemd}
+	c, err := opts.ParseString("[Service]\n" +
+		"ExecStartPre=/bin/a\nExecStartPre=/bin/b\n" +
+		"Environment=\"A=1\" B=2\n" +
+		"ExecStart=/bin/run\\\n  --flag\n" +
+		"[Install]\nWantedBy=a.target\nWantedBy=\nWantedBy=b.target\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := c.section("Service")
+	if got, want := service.ValuesOf("ExecStartPre"), []string{"/bin/a", "/bin/b"}; !slices.Equal(got, want) {
+		t.Errorf("ExecStartPre is %q, want %q", got, want)
+	}
+	if got := service.ValueOf("Environment"); got != `"A=1" B=2` {
+		t.Errorf("Environment is %q, want the quotes kept", got)
+	}
+	if got := service.ValueOf("ExecStart"); got != "/bin/run --flag" {
+		t.Errorf("ExecStart is %q, want the continued line joined by a space", got)
+	}
+	if got, want := c.section("Install").ValuesOf("WantedBy"), []string{"b.target"}; !slices.Equal(got, want) {
+		t.Errorf("WantedBy is %q, want %q once reset", got, want)
+	}
+
+	want := "[Service]\nExecStartPre=/bin/a\nExecStartPre=/bin/b\nEnvironment=\"A=1\" B=2\nExecStart=/bin/run --flag\n" +
+		"[Install]\nWantedBy=\nWantedBy=b.target\n"
+	if got := c.String(); got != want {
+		t.Errorf("written unit is\n%q\nwant\n%q", got, want)
+	}
+	if got := roundTrip(t, c, opts).String(); got != want {
+		t.Errorf("unit read back is\n%q\nwant\n%q", got, want)
+	}
+}
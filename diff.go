@@ -0,0 +1,276 @@
+package goini
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a section or option only present in the second configuration.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved is a section or option only present in the first configuration.
+	ChangeRemoved
+	// ChangeModified is an option whose values differ.
+	ChangeModified
+)
+
+// Change is a difference between two configurations. Option is empty for changes
+// of whole sections, in which case the options of the section are listed as well.
+type Change struct {
+	Kind      ChangeKind
+	Section   string
+	SubName   string
+	Option    string
+	OldValues []string // values in the first configuration, nil if added
+	NewValues []string // values in the second configuration, nil if removed
+}
+
+// Changeset is the list of changes between two configurations, see Diff.
+type Changeset struct {
+	From, To string // file paths of the compared configurations
+	Changes  []Change
+
+	fromLines, toLines []string // lines of the compared configurations as written, see String
+}
+
+// sectionKey identifies a section by name and subsection name, ignoring the case
+// of the name if fold is true.
+func sectionKey(s *Section, fold bool) string {
+	if fold {
+		return strings.ToLower(s.name) + "\x00" + s.subName
+	}
+	return s.name + "\x00" + s.subName
+}
+
+// firstSections returns the first instance of every section of c, in order.
+func firstSections(c *IniFile, fold bool) (sections []*Section, byKey map[string]*Section) {
+	byKey = make(map[string]*Section)
+	for _, s := range c.Sections() {
+		key := sectionKey(s, fold)
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = s
+			sections = append(sections, s)
+		}
+	}
+	return sections, byKey
+}
+
+// optionNamed returns the spelling of option in s, ignoring case if fold is true,
+// and false if s has no such option.
+func optionNamed(s *Section, option string, fold bool) (string, bool) {
+	for _, opt := range s.OptionNames() {
+		if opt == option || fold && strings.EqualFold(opt, option) {
+			return opt, true
+		}
+	}
+	return "", false
+}
+
+// Diff returns the changes turning a into b. Sections are matched by name and
+// subsection name, only the first instance of a duplicated section is compared.
+// Names of sections and options are compared regardless of case if either
+// configuration ignores case, see ParseOptions.IgnoreCase.
+// Removed and modified entries follow the order of a, added ones the order of b.
+func Diff(a, b *IniFile) Changeset {
+	cs := Changeset{From: a.FilePath(), To: b.FilePath(), fromLines: textLines(a.String()), toLines: textLines(b.String())}
+	fold := a.IgnoresCase() || b.IgnoresCase()
+	aSections, _ := firstSections(a, fold)
+	bSections, bByKey := firstSections(b, fold)
+	aKeys := make(map[string]bool)
+
+	for _, as := range aSections {
+		aKeys[sectionKey(as, fold)] = true
+		bs, ok := bByKey[sectionKey(as, fold)]
+		if !ok {
+			cs.Changes = append(cs.Changes, sectionChanges(ChangeRemoved, as)...)
+			continue
+		}
+		for _, opt := range as.OptionNames() {
+			oldValues := as.ValuesOf(opt)
+			bOpt, ok := optionNamed(bs, opt, fold)
+			switch newValues := bs.ValuesOf(bOpt); {
+			case !ok:
+				cs.Changes = append(cs.Changes, Change{Kind: ChangeRemoved, Section: as.name, SubName: as.subName, Option: opt, OldValues: oldValues})
+			case !slices.Equal(oldValues, newValues):
+				cs.Changes = append(cs.Changes, Change{Kind: ChangeModified, Section: as.name, SubName: as.subName, Option: opt, OldValues: oldValues, NewValues: newValues})
+			}
+		}
+		for _, opt := range bs.OptionNames() {
+			if _, ok := optionNamed(as, opt, fold); !ok {
+				cs.Changes = append(cs.Changes, Change{Kind: ChangeAdded, Section: bs.name, SubName: bs.subName, Option: opt, NewValues: bs.ValuesOf(opt)})
+			}
+		}
+	}
+	for _, bs := range bSections {
+		if !aKeys[sectionKey(bs, fold)] {
+			cs.Changes = append(cs.Changes, sectionChanges(ChangeAdded, bs)...)
+		}
+	}
+	return cs
+}
+
+// sectionChanges returns the change of a whole added or removed section followed by its options.
+func sectionChanges(kind ChangeKind, s *Section) []Change {
+	changes := []Change{{Kind: kind, Section: s.name, SubName: s.subName}}
+	for _, opt := range s.OptionNames() {
+		change := Change{Kind: kind, Section: s.name, SubName: s.subName, Option: opt}
+		if kind == ChangeAdded {
+			change.NewValues = s.ValuesOf(opt)
+		} else {
+			change.OldValues = s.ValuesOf(opt)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Empty returns true if there are no changes.
+func (cs Changeset) Empty() bool {
+	return len(cs.Changes) == 0
+}
+
+// textLines returns the lines of text, without line endings.
+func textLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffContext is the number of unchanged lines around the changes of a hunk.
+const diffContext = 3
+
+// String renders the differences between the configurations, as written by
+// IniFile.String, as a unified diff with three lines of context that patch(1) can
+// apply. Differences of comments and formatting are included, but only if there are
+// changes of values. It returns an empty string if there are no changes.
+func (cs Changeset) String() string {
+	if cs.Empty() {
+		return ""
+	}
+	from, to := cs.From, cs.To
+	if from == "" {
+		from = "a"
+	}
+	if to == "" {
+		to = "b"
+	}
+
+	ops := diffLines(cs.fromLines, cs.toLines)
+	var hunks [][2]int // ranges of ops
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := max(i-diffContext, 0), min(i+1+diffContext, len(ops))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("--- " + from + "\n+++ " + to + "\n")
+	aLine, bLine, next := 0, 0, 0 // lines of a and b before ops[next]
+	for _, hunk := range hunks {
+		for ; next < hunk[0]; next++ {
+			aLine, bLine = ops[next].advance(aLine, bLine)
+		}
+		aStart, bStart := aLine, bLine
+		for ; next < hunk[1]; next++ {
+			aLine, bLine = ops[next].advance(aLine, bLine)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(aStart, aLine-aStart), hunkRange(bStart, bLine-bStart))
+		for _, op := range ops[hunk[0]:hunk[1]] {
+			b.WriteString(string(op.kind) + op.text + "\n")
+		}
+	}
+	return b.String()
+}
+
+// hunkRange returns the range of count lines following line start in a hunk header.
+func hunkRange(start, count int) string {
+	if count > 0 {
+		start++
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// lineOp is an operation of an edit script, see diffLines.
+type lineOp struct {
+	kind byte // ' ' for a line kept, '-' for a line removed, '+' for a line added
+	text string
+}
+
+// advance returns the numbers of lines of both sides read once op is applied.
+func (op lineOp) advance(a, b int) (int, int) {
+	switch op.kind {
+	case '-':
+		return a + 1, b
+	case '+':
+		return a, b + 1
+	}
+	return a + 1, b + 1
+}
+
+// diffLines returns the shortest edit script turning the lines a into the lines b,
+// computed with Myers' algorithm, removed lines preceding added ones.
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1) // furthest x reached on each diagonal k, at v[offset+k]
+	var trace [][]int            // diagonals -d-1 to d+1 of v before step d, the only ones step d reads
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, offset := trace[d], d+1
+		k := x - y
+		prev := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prev = k + 1
+		}
+		prevX := v[offset+prev]
+		prevY := prevX - prev
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, lineOp{' ', a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			ops = append(ops, lineOp{'+', b[prevY]})
+		} else {
+			ops = append(ops, lineOp{'-', a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(ops)
+	return ops
+}
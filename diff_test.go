@@ -0,0 +1,100 @@
+package goini
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := ParseString("[kept]\nsame = 1\nchanged = a\nremoved = x\n[gone]\nk = v\n[remote \"origin\"]\nurl = a\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseString("[kept]\nsame = 1\nchanged = b\nadded = y\n[remote \"origin\"]\nurl = b\n[new]\nk = w\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Kind: ChangeModified, Section: "kept", Option: "changed", OldValues: []string{"a"}, NewValues: []string{"b"}},
+		{Kind: ChangeRemoved, Section: "kept", Option: "removed", OldValues: []string{"x"}},
+		{Kind: ChangeAdded, Section: "kept", Option: "added", NewValues: []string{"y"}},
+		{Kind: ChangeRemoved, Section: "gone"},
+		{Kind: ChangeRemoved, Section: "gone", Option: "k", OldValues: []string{"v"}},
+		{Kind: ChangeModified, Section: "remote", SubName: "origin", Option: "url", OldValues: []string{"a"}, NewValues: []string{"b"}},
+		{Kind: ChangeAdded, Section: "new"},
+		{Kind: ChangeAdded, Section: "new", Option: "k", NewValues: []string{"w"}},
+	}
+	if got := Diff(a, b).Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes are\n%+v\nwant\n%+v", got, want)
+	}
+	if cs := Diff(a, a); !cs.Empty() || cs.String() != "" {
+		t.Errorf("a configuration differs from itself: %+v", cs.Changes)
+	}
+}
+
+func TestDiffIgnoreCase(t *testing.T) {
+	opts := ParseOptions{IgnoreCase: true}
+	a, err := opts.ParseString("[Server]\nPort = 80\nHost = a\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := opts.ParseString("[server]\nport = 81\nhost = a\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{{Kind: ChangeModified, Section: "Server", Option: "Port", OldValues: []string{"80"}, NewValues: []string{"81"}}}
+	if got := Diff(a, b).Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes are\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestChangesetString(t *testing.T) {
+	a, err := ParseString("[s]\na = 1\nb = 2\nc = 3\nd = 4\ne = 5\nf = 6\ng = 7\nh = 8\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseString("[s]\na = 1\nb = 2\nc = 3\nd = four\ne = 5\nf = 6\ng = 7\nh = 8\ni = 9\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a\n+++ b\n" +
+		"@@ -2,8 +2,9 @@\n a=1\n b=2\n c=3\n-d=4\n+d=four\n e=5\n f=6\n g=7\n h=8\n+i=9\n"
+	if got := Diff(a, b).String(); got != want {
+		t.Errorf("diff is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	var a, b []string
+	for i := range 2000 {
+		a = append(a, fmt.Sprint(i))
+		if i%7 != 0 {
+			b = append(b, fmt.Sprint(i))
+		}
+		if i%11 == 0 {
+			b = append(b, "new")
+		}
+	}
+	var from, to []string
+	removed, added := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op.kind {
+		case '-':
+			from = append(from, op.text)
+			removed++
+		case '+':
+			to = append(to, op.text)
+			added++
+		default:
+			from, to = append(from, op.text), append(to, op.text)
+		}
+	}
+	if strings.Join(from, "\n") != strings.Join(a, "\n") || strings.Join(to, "\n") != strings.Join(b, "\n") {
+		t.Fatal("the edit script does not turn a into b")
+	}
+	if want := 286 + 182; removed+added != want {
+		t.Errorf("edit script has %d changes, want %d", removed+added, want)
+	}
+}
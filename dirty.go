@@ -0,0 +1,117 @@
+package goini
+
+import (
+	"slices"
+	"strings"
+)
+
+// IsModified returns true if the configuration changed since it was parsed or last saved.
+func (c *IniFile) IsModified() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.restructured || len(c.modified) > 0
+}
+
+// ModifiedKeys returns the paths, as accepted by Get, of the options whose values
+// changed since the configuration was parsed or last saved, sorted.
+func (c *IniFile) ModifiedKeys() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	sep := c.pathSeparator
+	if sep == "" {
+		sep = "."
+	}
+	keys := make([]string, 0, len(c.modified))
+	for key := range c.modified {
+		section, option, _ := strings.Cut(key, "\x00")
+		keys = append(keys, section+sep+option)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// markModified records that the value of option in section changed.
+func (c *IniFile) markModified(section, option string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.modified == nil {
+		c.modified = make(map[string]bool)
+	}
+	c.modified[optionKey(section, option)] = true
+	c.version++
+}
+
+// touch records a change of the configuration other than of the value of an option.
+func (c *IniFile) touch() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.restructure()
+}
+
+// restructure records a change of the configuration other than of the value of an
+// option. The caller must hold the lock.
+func (c *IniFile) restructure() {
+	c.restructured = true
+	c.version++
+}
+
+// clearModified forgets the changes of the configuration, once parsed or saved.
+func (c *IniFile) clearModified() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.modified, c.restructured = nil, false
+}
+
+// changed records the change of the value of option from oldValue to value and
+// notifies the subscribers, existed and exists telling whether the option existed
+// before and after the change. The lock of the section must not be held.
+func (s *Section) changed(option, oldValue string, existed bool, value string, exists bool) {
+	if s.file == nil || (oldValue == value && existed == exists) {
+		return
+	}
+	change := ValueChange{Section: s.Name(), SubName: s.SubName(), Option: option, OldValue: oldValue, NewValue: value,
+		Existed: existed, Exists: exists}
+	s.file.markModified(change.Section, option)
+	s.file.record(edit{changes: []ValueChange{change}})
+	s.file.notify([]ValueChange{change})
+}
+
+// renamed records the renaming of option oldName, whose value is value, to newName
+// as a single change of the history and notifies the subscribers of both names.
+// The lock of the section must not be held.
+func (s *Section) renamed(oldName, newName, value string) {
+	if s.file == nil {
+		return
+	}
+	name, subName := s.Name(), s.SubName()
+	changes := []ValueChange{
+		{Section: name, SubName: subName, Option: oldName, OldValue: value, Existed: true},
+		{Section: name, SubName: subName, Option: newName, NewValue: value, Exists: true},
+	}
+	for _, change := range changes {
+		s.file.markModified(name, change.Option)
+	}
+	s.file.record(edit{changes: changes, rename: true})
+	s.file.notify(changes)
+}
+
+// touchOption records a change of the values of option other than of its first value.
+// The lock of the section must not be held.
+func (s *Section) touchOption(option string) {
+	if s.file != nil {
+		s.file.markModified(s.Name(), option)
+	}
+}
+
+// touch records a change of the section other than of the value of an option.
+// The lock of the section must not be held.
+func (s *Section) touch() {
+	if s.file != nil {
+		s.file.touch()
+	}
+}
@@ -0,0 +1,186 @@
+package goini
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encode builds a configuration from the struct v, or a pointer to it, using
+// the same mapping rules as Decode. Sections and options are added in field order.
+func Encode(v interface{}) (*IniFile, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("Encode requires a non-nil struct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("Encode requires a struct")
+	}
+
+	c := NewIniFile("")
+	global := c.AddSection("global")
+	if err := c.encodeFields("", global, rv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// encodeFields adds the fields of the struct rv as options of section, and its
+// struct fields as subsections of the section name.
+func (c *IniFile) encodeFields(name string, section *Section, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		fieldName, skip := fieldName(f)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := c.encodeFields(name, section, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isNameField(f) {
+			continue
+		}
+		if isSectionSliceField(f.Type) {
+			if err := c.encodeSections(subsectionName(name, fieldName), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isSectionMapField(f.Type) {
+			if err := c.encodeMap(subsectionName(name, fieldName), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isSectionField(f.Type) {
+			if err := encodeOption(section, fieldName, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		sectionName := subsectionName(name, fieldName)
+		if err := c.encodeFields(sectionName, c.AddSection(sectionName), fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSections adds a section named name per element of the slice of structs, or
+// pointers to structs, fv, a `[name "sub"]` one if the field of the element tagged
+// `ini:",name"` is set to "sub".
+func (c *IniFile) encodeSections(name string, fv reflect.Value) error {
+	for i := 0; i < fv.Len(); i++ {
+		ev := fv.Index(i)
+		if ev.Kind() == reflect.Ptr {
+			if ev.IsNil() {
+				continue
+			}
+			ev = ev.Elem()
+		}
+		var subName string
+		for j := 0; j < ev.NumField(); j++ {
+			if f := ev.Type().Field(j); isNameField(f) && f.Type.Kind() == reflect.String {
+				subName = ev.Field(j).String()
+			}
+		}
+		if err := c.encodeFields(name, c.AddSubsection(name, subName), ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap adds a section named name with an option per entry of the map fv,
+// sorted by key. Nothing is added for nil maps.
+func (c *IniFile) encodeMap(name string, fv reflect.Value) error {
+	if fv.IsNil() {
+		return nil
+	}
+	keys := fv.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+	section := c.AddSection(name)
+	for _, key := range keys {
+		if err := encodeOption(section, key.String(), fv.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeOption adds option with the text representation of fv to section.
+func encodeOption(section *Section, option string, fv reflect.Value) error {
+	if fv.Kind() == reflect.Ptr && fv.IsNil() {
+		return nil
+	}
+	value, err := formatValue(fv)
+	if err != nil {
+		return fmt.Errorf("unable to encode %s in section %s: %v", option, section.Name(), err)
+	}
+	section.Add(option, value)
+	return nil
+}
+
+// formatValue returns the text representation of fv.
+func formatValue(fv reflect.Value) (string, error) {
+	if fv.Kind() != reflect.Ptr && fv.CanInterface() {
+		if m, ok := fv.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+		if decoderOf(fv.Type()) != nil {
+			return fmt.Sprint(fv.Interface()), nil
+		}
+	}
+	if fv.Type() == durationType {
+		return time.Duration(fv.Int()).String(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	case reflect.Slice:
+		parts := make([]string, fv.Len())
+		for i := range parts {
+			part, err := formatValue(fv.Index(i))
+			if err != nil {
+				return "", err
+			}
+			if strings.ContainsAny(part, ",\"") || part != strings.TrimSpace(part) {
+				part = strconv.Quote(part)
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, ", "), nil
+	case reflect.Ptr:
+		return formatValue(fv.Elem())
+	}
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
@@ -0,0 +1,145 @@
+package goini
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+)
+
+// Encoding is the character encoding of a configuration file.
+type Encoding int
+
+const (
+	// EncodingUTF8 is UTF-8 without byte order mark.
+	EncodingUTF8 Encoding = iota
+	// EncodingUTF8BOM is UTF-8 starting with a byte order mark.
+	EncodingUTF8BOM
+	// EncodingUTF16LE is little-endian UTF-16 starting with a byte order mark.
+	EncodingUTF16LE
+	// EncodingUTF16BE is big-endian UTF-16 starting with a byte order mark.
+	EncodingUTF16BE
+)
+
+// Encoding returns the character encoding of the parsed file.
+func (c *IniFile) Encoding() Encoding {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.encoding
+}
+
+// decodeBOM detects the byte order mark at the start of r and returns a reader of
+// the UTF-8 content without it. UTF-16 content is only decoded if decodeUTF16 is true.
+func decodeBOM(r io.Reader, decodeUTF16 bool) (io.Reader, Encoding, error) {
+	br := bufio.NewReader(r)
+	bom, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(bom, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+		return br, EncodingUTF8BOM, nil
+	case decodeUTF16 && bytes.HasPrefix(bom, []byte{0xFF, 0xFE}):
+		return decodeUTF16Reader(br, binary.LittleEndian, EncodingUTF16LE)
+	case decodeUTF16 && bytes.HasPrefix(bom, []byte{0xFE, 0xFF}):
+		return decodeUTF16Reader(br, binary.BigEndian, EncodingUTF16BE)
+	}
+	return br, EncodingUTF8, nil
+}
+
+// decodeUTF16Reader returns a reader of the UTF-16 content of r, after its byte order mark, as UTF-8.
+func decodeUTF16Reader(r io.Reader, order binary.ByteOrder, encoding Encoding) (io.Reader, Encoding, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, encoding, err
+	}
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2+2*i:])
+	}
+	return bytes.NewReader([]byte(string(utf16.Decode(units)))), encoding, nil
+}
+
+// encodeText returns the UTF-8 text encoded with encoding, with its byte order mark.
+func encodeText(text []byte, encoding Encoding) []byte {
+	var order binary.ByteOrder
+	switch encoding {
+	case EncodingUTF8BOM:
+		return append([]byte{0xEF, 0xBB, 0xBF}, text...)
+	case EncodingUTF16LE:
+		order = binary.LittleEndian
+	case EncodingUTF16BE:
+		order = binary.BigEndian
+	default:
+		return text
+	}
+	units := make([]rune, 0, utf8.RuneCount(text)+1)
+	units = append(units, '\uFEFF')
+	units = append(units, bytes.Runes(text)...)
+	encoded := utf16.Encode(units)
+	out := make([]byte, 2*len(encoded))
+	for i, u := range encoded {
+		order.PutUint16(out[2*i:], u)
+	}
+	return out
+}
+
+// CharsetDecoder returns a reader of the content of r transcoded to UTF-8.
+type CharsetDecoder func(r io.Reader) io.Reader
+
+// charsets holds the decoders registered with RegisterCharset, by lowercase name.
+var (
+	charsetsMutex sync.RWMutex
+	charsets      = map[string]CharsetDecoder{}
+)
+
+// charsetAliases maps common names missing from the IANA and WHATWG indexes to
+// names they know.
+var charsetAliases = map[string]string{
+	"latin-1": "iso-8859-1",
+	"utf8":    "utf-8",
+}
+
+// RegisterCharset makes the charset name available to ParseOptions.Charset, or
+// replaces the decoder of a charset of golang.org/x/text.
+func RegisterCharset(name string, decoder CharsetDecoder) {
+	charsetsMutex.Lock()
+	defer charsetsMutex.Unlock()
+
+	charsets[strings.ToLower(name)] = decoder
+}
+
+// decodeCharset returns a reader of the content of r in charset transcoded to UTF-8.
+// Charsets are looked up among those registered, then by their IANA name, then by
+// their WHATWG label.
+func decodeCharset(r io.Reader, charset string) (io.Reader, error) {
+	name := strings.ToLower(charset)
+	if alias, ok := charsetAliases[name]; ok {
+		name = alias
+	}
+	if name == "" || name == "utf-8" {
+		return r, nil
+	}
+	charsetsMutex.RLock()
+	decoder, ok := charsets[name]
+	charsetsMutex.RUnlock()
+	if ok {
+		return decoder(r), nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if enc == nil {
+		enc, err = htmlindex.Get(name)
+	}
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	return transform.NewReader(r, enc.NewDecoder()), nil
+}
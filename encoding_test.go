@@ -0,0 +1,103 @@
+package goini
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Text returns text encoded as UTF-16 with a byte order mark.
+func utf16Text(text string, bigEndian bool) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune("\uFEFF" + text)) {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+// saveEncoded parses data from a file, saves it again with opts and returns the file content.
+func saveEncoded(t *testing.T, data []byte, parse ParseOptions, save SaveOptions) (*IniFile, []byte) {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "encoded.ini")
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := parse.Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSaveOptions(save)
+	c.section("s").Add("k", "café")
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return c, []byte(readFile(t, filePath))
+}
+
+func TestUTF8BOM(t *testing.T) {
+	data := []byte("\xEF\xBB\xBF[s]\nk = v\n")
+	c, saved := saveEncoded(t, data, ParseOptions{}, SaveOptions{KeepEncoding: true})
+	if got := c.Encoding(); got != EncodingUTF8BOM {
+		t.Errorf("encoding is %d, want EncodingUTF8BOM", got)
+	}
+	if want := "\xEF\xBB\xBF[s]\nk=café\n"; string(saved) != want {
+		t.Errorf("saved file is %q, want %q", saved, want)
+	}
+	if _, saved := saveEncoded(t, data, ParseOptions{}, SaveOptions{}); bytes.HasPrefix(saved, []byte("\xEF\xBB\xBF")) {
+		t.Errorf("saved file is %q, want no byte order mark without KeepEncoding", saved)
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	for _, bigEndian := range []bool{false, true} {
+		want := EncodingUTF16LE
+		if bigEndian {
+			want = EncodingUTF16BE
+		}
+		c, saved := saveEncoded(t, utf16Text("[s]\nk = v\n", bigEndian), ParseOptions{DecodeUTF16: true}, SaveOptions{KeepEncoding: true})
+		if got := c.Encoding(); got != want {
+			t.Errorf("encoding is %d, want %d", got, want)
+		}
+		if expected := utf16Text("[s]\nk=café\n", bigEndian); !bytes.Equal(saved, expected) {
+			t.Errorf("saved file is %q, want %q", saved, expected)
+		}
+	}
+	if c, err := (ParseOptions{}).ParseBytes(utf16Text("[s]\nk = v\n", false)); err == nil && c.section("s") != nil {
+		t.Error("UTF-16 content decoded without DecodeUTF16")
+	}
+}
+
+func TestCharset(t *testing.T) {
+	for _, charset := range []string{"windows-1252", "latin-1", "ISO-8859-1"} {
+		c, err := ParseOptions{Charset: charset}.ParseBytes([]byte("[s]\nk = caf\xe9\n"))
+		if err != nil {
+			t.Fatalf("%s: %v", charset, err)
+		}
+		if got := c.section("s").ValueOf("k"); got != "café" {
+			t.Errorf("%s: k is %q, want café", charset, got)
+		}
+	}
+	if _, err := (ParseOptions{Charset: "no-such-charset"}).ParseString("[s]\n"); err == nil {
+		t.Error("parse with an unknown charset succeeded")
+	}
+
+	RegisterCharset("x-upper-test", func(r io.Reader) io.Reader {
+		data, _ := io.ReadAll(r)
+		return strings.NewReader(strings.ToUpper(string(data)))
+	})
+	c, err := ParseOptions{Charset: "X-Upper-Test"}.ParseString("[s]\nk = v\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.section("S").ValueOf("K"); got != "V" {
+		t.Errorf("K is %q, want the value decoded by the registered charset", got)
+	}
+}
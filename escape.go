@@ -0,0 +1,84 @@
+package goini
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Escape returns s with the escape sequences written by Windows-style INI tools:
+// backslashes, newlines, tabs, carriage returns, ';' and '#' are written as \\, \n,
+// \t, \r, \; and \#, and the characters outside printable ASCII up to U+FFFF as \x
+// followed by four hexadecimal digits, \x00e9 for 'é'. See Unescape.
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == ';' || r == '#':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case (r < ' ' || r > '~') && r <= 0xFFFF:
+			fmt.Fprintf(&b, `\x%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Unescape replaces the escape sequences written by Escape in s. Other backslashes
+// are kept.
+func Unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\', ';', '#':
+			b.WriteByte(c)
+		case 'x':
+			r, err := strconv.ParseUint(s[i+2:min(i+6, len(s))], 16, 32)
+			if err != nil || i+6 > len(s) {
+				b.WriteByte('\\')
+				continue
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte('\\')
+			continue
+		}
+		i++
+	}
+	return b.String()
+}
+
+// escapeValue returns value escaped with Escape if it can be written unquoted, see
+// SaveOptions.EscapeValues.
+func escapeValue(value string) (string, bool) {
+	escaped := Escape(value)
+	if escaped != strings.TrimSpace(escaped) || escaped[0] == '"' || escaped[0] == '\'' || !utf8.ValidString(value) {
+		return value, false
+	}
+	return escaped, true
+}
@@ -0,0 +1,280 @@
+package goini
+
+import (
+	"slices"
+	"strings"
+)
+
+// rawSection holds the lines of a section as they were parsed, see ParseOptions.PreserveFormatting.
+type rawSection struct {
+	header     string // header line, empty for the global section and sections of included files
+	headerLine int    // line number of the header
+	name       string // name, subsection name and parent of the section when parsed
+	subName    string
+	parent     string
+	pre        []rawLine // comment and blank lines preceding the header
+	lines      []rawLine // lines following the header
+}
+
+// clone returns a copy of the record, or nil if r is nil.
+func (r *rawSection) clone() *rawSection {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.pre, clone.lines = slices.Clone(r.pre), slices.Clone(r.lines)
+	return &clone
+}
+
+// rawLine is a parsed line, made of one or more physical lines.
+type rawLine struct {
+	text   string // physical lines, without line endings
+	line   int    // line number of the first physical line
+	option string // option whose value is set by the line, empty for other lines
+	index  int    // index of the value among the values of the option, see Section.ValuesOf
+	value  string // value set by the line when parsed
+	prefix string // text preceding the value, empty if the line must be regenerated when the value changes
+	suffix string // text following the value, like an inline comment
+	hidden bool   // line of an included file, written only if the value changed
+}
+
+// newRawSection returns the record of the lines of the section s, kept by the configuration.
+func (p *parser) newRawSection(s *Section) *rawSection {
+	r := &rawSection{name: s.name, subName: s.subName, parent: s.parent}
+	p.file.raws = append(p.file.raws, r)
+	return r
+}
+
+// recordLine records the line being parsed as a line that does not set an option.
+func (p *parser) recordLine() {
+	p.recorded = true
+	if !p.preserve || p.including > 0 {
+		return
+	}
+	p.active.raw.lines = append(p.active.raw.lines, rawLine{text: p.rawText, line: p.lineNo})
+}
+
+// recordHeader records the section header being parsed, previous being the section
+// active until then.
+func (p *parser) recordHeader(previous *Section) {
+	p.recorded = true
+	if !p.preserve {
+		return
+	}
+	s := p.active
+	if p.including > 0 {
+		if s.raw == nil {
+			s.raw = p.newRawSection(s)
+		}
+		return
+	}
+	if s.raw != nil && s.raw.header != "" {
+		// section declared again and merged
+		s.raw.lines = append(s.raw.lines, rawLine{text: p.rawText, line: p.lineNo})
+		return
+	}
+	if s.raw == nil {
+		s.raw = p.newRawSection(s)
+	}
+	s.raw.header, s.raw.headerLine = p.rawText, p.lineNo
+
+	// the comment and blank lines preceding the header belong to the section
+	if previous != s {
+		lines := previous.raw.lines
+		i := len(lines)
+		for i > 0 && lines[i-1].option == "" {
+			i--
+		}
+		s.raw.pre, previous.raw.lines = lines[i:], lines[:i]
+	}
+}
+
+// recordOption records the line being parsed as setting the last value of option.
+func (p *parser) recordOption(option string) {
+	p.recorded = true
+	p.setPosition(option)
+	if !p.preserve {
+		return
+	}
+	values := p.active.ValuesOf(option)
+	line := rawLine{option: option, index: len(values) - 1, value: values[len(values)-1], hidden: p.including > 0}
+	if !line.hidden {
+		line.text, line.line = p.rawText, p.lineNo
+		if !strings.Contains(p.rawText, "\n") {
+			line.prefix, line.suffix = p.valueBounds(p.rawText)
+		}
+	}
+	p.active.raw.lines = append(p.active.raw.lines, line)
+}
+
+// forgetOption turns the recorded lines setting option in the active section into
+// lines that do not set it, once their values are overridden.
+func (p *parser) forgetOption(option string) {
+	if !p.preserve {
+		return
+	}
+	lines := p.active.raw.lines[:0]
+	for _, line := range p.active.raw.lines {
+		if line.option == option {
+			if line.hidden {
+				continue
+			}
+			line = rawLine{text: line.text}
+		}
+		lines = append(lines, line)
+	}
+	p.active.raw.lines = lines
+}
+
+// recordContinuation appends the line being parsed, continuing the value of option, to the line of the option.
+func (p *parser) recordContinuation(option string) {
+	p.recorded = true
+	if !p.preserve || p.including > 0 {
+		return
+	}
+	lines := p.active.raw.lines
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].option == option {
+			lines[i].text += "\n" + p.rawText
+			lines[i].value = p.active.ValueOfRaw(option)
+			lines[i].prefix, lines[i].suffix = "", ""
+			return
+		}
+	}
+}
+
+// valueBounds returns the text preceding and following the value of the option line text.
+func (p *parser) valueBounds(text string) (prefix, suffix string) {
+	i := delimiterIndex(text, p.opts.Delimiters)
+	if i == -1 {
+		return "", ""
+	}
+	content := text
+	if p.opts.AllowInlineComments {
+		content, _ = splitInlineComment(text, p.opts.Delimiters, p.opts.commentPrefixes())
+	}
+	if p.opts.Whitespace != TrimWhitespace {
+		return text[:i+1], text[len(content):]
+	}
+	start := len(text) - len(strings.TrimLeft(text[i+1:], " \t"))
+	end := len(strings.TrimRight(content, " \t"))
+	if end < start {
+		end = start
+	}
+	return text[:start], text[end:]
+}
+
+// rawPlan describes how the parsed lines of a section are written.
+type rawPlan struct {
+	texts []*string // text of each parsed line, nil if the line is not written
+	added []string  // lines of the options and values that were not parsed
+	at    int       // index of the parsed line the added lines are written before
+}
+
+// plan returns how the parsed lines of the section are written: the lines of the
+// options whose values changed are regenerated, new options and values are added
+// after the last option line. The caller must hold the lock.
+func (s *Section) plan(opts SaveOptions, dialect Dialect) rawPlan {
+	r := s.raw
+	plan := rawPlan{texts: make([]*string, len(r.lines)), at: -1}
+	written := make(map[string][]bool)
+	for i, line := range r.lines {
+		text := line.text
+		if line.option == "" {
+			plan.texts[i] = &text
+			continue
+		}
+		values := s.valuesOf(line.option)
+		if line.index >= len(values) {
+			continue
+		}
+		if written[line.option] == nil {
+			written[line.option] = make([]bool, len(values))
+		}
+		written[line.option][line.index] = true
+		value := values[line.index]
+		switch {
+		case value == line.value && line.hidden:
+			continue
+		case value == line.value:
+		case line.prefix != "" && !(opts.IndentedContinuation && strings.Contains(value, "\n")):
+			if dialect != DialectSystemd {
+				value = quoteValue(value, opts.QuoteInlineComments)
+			}
+			text = line.prefix + value + line.suffix
+		default:
+			text = formatOption(line.option, value, opts, dialect, 0)
+		}
+		plan.texts[i] = &text
+		plan.at = i + 1
+	}
+
+	for _, opt := range s.orderedOptions {
+		for i, value := range s.valuesOf(opt) {
+			if w := written[opt]; w != nil && w[i] {
+				continue
+			}
+			if comment, ok := s.comments[opt]; ok && i == 0 && written[opt] == nil {
+				plan.added = append(plan.added, comment)
+			}
+			if s.isBare(opt) {
+				plan.added = append(plan.added, opt)
+			} else {
+				plan.added = append(plan.added, formatOption(opt, value, opts, dialect, 0))
+			}
+		}
+	}
+	if plan.at == -1 {
+		plan.at = len(r.lines)
+		for plan.at > 0 && r.lines[plan.at-1].option == "" && strings.TrimSpace(r.lines[plan.at-1].text) == "" {
+			plan.at--
+		}
+	}
+	return plan
+}
+
+// headerChanged returns true if the section was renamed or its parent changed since
+// it was parsed. The caller must hold the lock.
+func (s *Section) headerChanged() bool {
+	r := s.raw
+	return r.header == "" || s.name != r.name || s.subName != r.subName || s.parent != r.parent
+}
+
+// formatRaw returns the text representation of a section from its parsed lines, see
+// plan. The order of the options and changes to the comments are not taken into account.
+func (s *Section) formatRaw(opts SaveOptions, dialect Dialect) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	r := s.raw
+	plan := s.plan(opts, dialect)
+	var body []string
+	for i, text := range plan.texts {
+		if i == plan.at {
+			body = append(body, plan.added...)
+		}
+		if text != nil {
+			body = append(body, *text)
+		}
+	}
+	if plan.at == len(plan.texts) {
+		body = append(body, plan.added...)
+	}
+
+	var lines []string
+	for _, line := range r.pre {
+		lines = append(lines, line.text)
+	}
+	if !s.global && (r.header != "" || len(body) > 0) {
+		header := r.header
+		if s.headerChanged() {
+			header = s.headerLine()
+		}
+		lines = append(lines, header)
+	}
+	lines = append(lines, body...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
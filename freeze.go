@@ -0,0 +1,25 @@
+package goini
+
+import "errors"
+
+// ErrFrozen is returned by the methods modifying a configuration once it is frozen.
+var ErrFrozen = errors.New("configuration is frozen")
+
+// Freeze makes the configuration and its sections read-only for good. Methods
+// modifying them return ErrFrozen from then on; those without an error result,
+// such as Section.Add or SetComment, leave them unchanged. AddSection returns the
+// existing section, or one that is not part of the configuration. Saving the
+// configuration is still possible, and Clone returns a copy that is not frozen.
+func (c *IniFile) Freeze() {
+	c.frozen.Store(true)
+}
+
+// Frozen returns true if the configuration is read-only, see Freeze.
+func (c *IniFile) Frozen() bool {
+	return c.frozen.Load()
+}
+
+// frozen returns true if the section belongs to a frozen configuration.
+func (s *Section) frozen() bool {
+	return s.file != nil && s.file.Frozen()
+}
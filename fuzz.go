@@ -0,0 +1,107 @@
+package goini
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrRoundTrip is wrapped by the error CheckRoundTrip returns when a configuration
+// does not parse back to the same sections, options and values once written.
+var ErrRoundTrip = errors.New("round trip mismatch")
+
+// CheckRoundTrip parses data, writes the configuration and parses the result again,
+// checking that both configurations hold the same sections, options, values and
+// comments in the same order and that writing the second one produces the same text.
+// An error is returned if data cannot be parsed or the round trip does not hold. It
+// never panics.
+func CheckRoundTrip(data []byte) error {
+	c, err := ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	text := c.String()
+	again, err := ParseString(text)
+	if err != nil {
+		return fmt.Errorf("%w: written configuration does not parse: %v", ErrRoundTrip, err)
+	}
+	if err := sameModel(c, again); err != nil {
+		return err
+	}
+	if written := again.String(); written != text {
+		return fmt.Errorf("%w: output changed from %q to %q", ErrRoundTrip, text, written)
+	}
+	return nil
+}
+
+// sameModel returns an error wrapping ErrRoundTrip describing the first difference
+// between the sections, options, values and comments of a and b.
+func sameModel(a, b *IniFile) error {
+	as, bs := a.Sections(), b.Sections()
+	if len(as) != len(bs) {
+		return fmt.Errorf("%w: %d sections instead of %d", ErrRoundTrip, len(bs), len(as))
+	}
+	for i, s := range as {
+		t := bs[i]
+		if s.Name() != t.Name() || s.SubName() != t.SubName() || s.Parent() != t.Parent() {
+			return fmt.Errorf("%w: section %s instead of %s", ErrRoundTrip, t.header(), s.header())
+		}
+		if s.Comment() != t.Comment() {
+			return fmt.Errorf("%w: comment %q instead of %q for section %s", ErrRoundTrip, t.Comment(), s.Comment(), s.header())
+		}
+		names := s.OptionNames()
+		if !slices.Equal(names, t.OptionNames()) {
+			return fmt.Errorf("%w: options %q instead of %q in section %s", ErrRoundTrip, t.OptionNames(), names, s.header())
+		}
+		for _, opt := range names {
+			if !slices.Equal(s.rawValuesOf(opt), t.rawValuesOf(opt)) {
+				return fmt.Errorf("%w: values %q instead of %q for %s in section %s",
+					ErrRoundTrip, t.rawValuesOf(opt), s.rawValuesOf(opt), opt, s.header())
+			}
+			if s.CommentFor(opt) != t.CommentFor(opt) {
+				return fmt.Errorf("%w: comment %q instead of %q for %s in section %s",
+					ErrRoundTrip, t.CommentFor(opt), s.CommentFor(opt), opt, s.header())
+			}
+		}
+	}
+	return nil
+}
+
+// rawValuesOf returns the values of option as parsed, shadow values included.
+func (s *Section) rawValuesOf(option string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.options[option]
+	if !ok {
+		return nil
+	}
+	return append([]string{value}, s.shadows[option]...)
+}
+
+// FuzzCorpus returns seed inputs for fuzzing CheckRoundTrip, covering the syntax the parser accepts.
+func FuzzCorpus() [][]byte {
+	seeds := []string{
+		"",
+		"key=value\n",
+		"[section]\nkey = value\n",
+		"; comment\n# comment\n[a]\n; option comment\nx=1 ; not inline\n",
+		"[a]\nx=1\n[a]\nx=2\n",
+		"[remote \"origin\"]\nurl = git@example.com:repo.git\n",
+		"[child : parent]\nkey=value\n[parent]\nother=1\n",
+		"[a]\nq=\"quoted \\\"value\\\"\\n\"\ns=' single '\n",
+		"[a]\nk[]=1\nk[]=2\n",
+		"[a]\nempty=\nbare\nx:y\n",
+		"[a]\r\nx=1\r\n",
+		"\ufeff[bom]\nx=1\n",
+		"[a]\nname[de_DE]=Name\nname=name\n",
+		"[[a]]\n[]\n[a\n=\n==\n:=\n",
+		"[db]\nconnstr = host=a;user=b\nurl: http://h:80/?a=b\npath=C:\\dir\n",
+		"[s]\n  # indented\nk=v\n",
+	}
+	corpus := make([][]byte, len(seeds))
+	for i, seed := range seeds {
+		corpus[i] = []byte(seed)
+	}
+	return corpus
+}
@@ -0,0 +1,17 @@
+package goini
+
+import (
+	"errors"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range FuzzCorpus() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := CheckRoundTrip(data); errors.Is(err, ErrRoundTrip) {
+			t.Fatal(err)
+		}
+	})
+}
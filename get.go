@@ -0,0 +1,17 @@
+package goini
+
+import "reflect"
+
+// Get returns the value of the specified option converted to T. T may be a string,
+// bool, integer, float, time.Duration, a slice of those read as a comma separated
+// list, any type whose pointer implements encoding.TextUnmarshaler, or a type with a
+// decoder registered with RegisterDecoder, which takes precedence.
+func Get[T any](section *Section, option string) (T, error) {
+	var v T
+	value, err := section.value(option)
+	if err != nil {
+		return v, err
+	}
+	err = setValue(reflect.ValueOf(&v).Elem(), value)
+	return v, err
+}
@@ -0,0 +1,201 @@
+package goini
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MatchSection returns a section holding the options of every section whose name,
+// read as an EditorConfig glob pattern, matches path, as an .editorconfig file is
+// applied. Options of later sections override those of earlier ones. The global
+// section is never matched.
+//
+// Patterns without a slash match the base name of path at any depth, the others
+// match path relative to the directory of the configuration file. Patterns support
+// "*" (any characters but '/'), "**" (any characters), "?", "[chars]", "[!chars]",
+// "{a,b}" alternatives and "{n1..n2}" integer ranges; a backslash escapes the next
+// character. The returned section is not part of the configuration.
+func (c *IniFile) MatchSection(path string) *Section {
+	path = filepath.ToSlash(c.relativePath(path))
+	path = strings.TrimPrefix(path, "./")
+
+	matched := &Section{name: path, options: make(map[string]string)}
+	for _, section := range c.Sections() {
+		if section.global || !matchGlob(section.name, path) {
+			continue
+		}
+		for _, opt := range section.OptionNames() {
+			matched.setValues(opt, section.ValuesOf(opt))
+		}
+	}
+	return matched
+}
+
+// relativePath returns path relative to the directory of the configuration file if
+// both are absolute and path is inside that directory, otherwise path unchanged.
+func (c *IniFile) relativePath(path string) string {
+	filePath := c.FilePath()
+	if filePath == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// matchGlob returns true if path matches the EditorConfig glob pattern.
+func matchGlob(pattern, path string) bool {
+	g := &globTranslator{}
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(pattern, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(g.translate(strings.TrimPrefix(pattern, "/")))
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	groups := re.FindStringSubmatch(path)
+	if groups == nil {
+		return false
+	}
+	for i, r := range g.ranges {
+		if groups[i+1] == "" {
+			continue // range in an alternative that did not match
+		}
+		n, err := strconv.Atoi(groups[i+1])
+		if err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// globRange matches the "{n1..n2}" integer ranges of a pattern.
+var globRange = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// globTranslator translates glob patterns to regular expressions. The integer
+// ranges are matched by capturing groups and checked afterwards.
+type globTranslator struct {
+	ranges [][2]int
+}
+
+// translate returns the regular expression matching pattern, without anchors.
+func (g *globTranslator) translate(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				b.WriteString(".*")
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			i += end + 1
+			b.WriteString("[")
+			if class[0] == '!' {
+				b.WriteString("^")
+				class = class[1:]
+			}
+			for j := 0; j < len(class); j++ {
+				if strings.IndexByte(`\[]^`, class[j]) != -1 {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(class[j])
+			}
+			b.WriteString("]")
+		case '{':
+			end := closingBrace(pattern[i:])
+			if end == -1 {
+				b.WriteString(`\{`)
+				continue
+			}
+			inner := pattern[i+1 : i+end]
+			i += end
+			if m := globRange.FindStringSubmatch(inner); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				g.ranges = append(g.ranges, [2]int{min(lo, hi), max(lo, hi)})
+				b.WriteString(`([+-]?\d+)`)
+				continue
+			}
+			alternatives := splitAlternatives(inner)
+			if len(alternatives) < 2 {
+				b.WriteString(regexp.QuoteMeta("{" + inner + "}"))
+				continue
+			}
+			for j, alternative := range alternatives {
+				alternatives[j] = g.translate(alternative)
+			}
+			b.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// closingBrace returns the index of the brace closing the one starting s, or -1.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the content of a brace expression on its top level commas.
+func splitAlternatives(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -1,29 +1,88 @@
 package goini
 
 import (
-	"sync"
-	"container/list"
-	"path"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
 	"os"
-	"bufio"
-	"strings"
+	"path/filepath"
 	"regexp"
-	"fmt"
-	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type IniFile struct {
-	filePath string
-	sections map[string]*list.List
-	mutex    sync.RWMutex
+	filePath        string
+	sections        map[string][]*Section
+	mutex           sync.RWMutex
+	saving          sync.Mutex // held while saving, so that files are written in the order serialized
 	orderedSections []string
+	saveOptions     SaveOptions
+	expandEnv       bool
+	useDefaults     bool
+	sectionPolicy   DuplicateSectionPolicy
+	pathSeparator   string
+	envPrefix       string
+	envMapper       EnvMapper
+	dialect         Dialect
+	backupPath      string // backup made by the last save
+	newline         string // line ending of the parsed file, "\n" if empty
+	encoding        Encoding
+	parseOptions    ParseOptions  // options the configuration was parsed with
+	raws            []*rawSection // lines of the parsed sections, see ParseOptions.PreserveFormatting
+	snapshot        atomic.Pointer[Snapshot]
+	loaded          fileState                     // state of the file when parsed or saved, see ReloadIfChanged
+	loadedSum       [sha256.Size]byte             // SHA-256 of the file when parsed or saved
+	subs            map[subKey][]chan ValueChange // channels by option subscribed to, see Subscribe
+	batchSubs       []chan []ValueChange          // see SubscribeChanges
+	subsMutex       sync.Mutex
+	subscribed      atomic.Int64        // number of subscriptions
+	frozen          atomic.Bool         // see Freeze
+	modified        map[string]bool     // option keys of the options changed since loaded or saved
+	restructured    bool                // sections, comments or order changed since loaded or saved
+	version         uint64              // incremented on each change recorded in modified or restructured
+	history         []edit              // changes that can be undone, the last one first undone
+	redo            []edit              // changes undone that can be redone
+	historyLimit    int                 // maximum length of the history, see SetHistoryLimit
+	aliases         map[string][]string // former names of options by option key, see UseAliases
+	ignoreCase      atomic.Bool         // see SetIgnoreCase
+	globalName      string              // name of the global section, "global" if empty
 }
 
+// Errors returned when looking up, adding or renaming sections and options, wrapped with the name.
+var (
+	ErrSectionNotFound = errors.New("section not found")
+	ErrOptionNotFound  = errors.New("option not found")
+	ErrSectionExists   = errors.New("section already exists")
+	ErrOptionExists    = errors.New("option already exists")
+)
+
+// DefaultSectionName is the name of the section providing default values,
+// see IniFile.SetUseDefaults.
+const DefaultSectionName = "DEFAULT"
+
+// ErrNoFilePath is returned by Save when the configuration has no file path.
+var ErrNoFilePath = errors.New("configuration has no file path")
+
+// New returns an empty configuration without file path, to be built in memory.
+// Use SetFilePath before Save, or SaveAs, to write it to a file.
+func New() *IniFile {
+	return NewIniFile("")
+}
+
+// NewIniFile returns an empty configuration that is saved to filePathArg.
 func NewIniFile(filePathArg string) *IniFile {
 	return &IniFile{
-		filePath:filePathArg,
-		sections:make(map[string]*list.List),
-    }
+		filePath: filePathArg,
+		sections: make(map[string][]*Section),
+	}
 }
 
 //
@@ -34,231 +93,987 @@ func isSection(section string) bool {
 	return strings.HasPrefix(section, "[")
 }
 
-// Read parses a specified configuration file and returns a Configuration instance.
+// indexOf returns the index of item in list, or -1.
+func indexOf(list []string, item string) int {
+	for i, s := range list {
+		if s == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveItem moves item just before, or after, mark in list and returns the updated list.
+// ok is false if either item or mark is not in list.
+func moveItem(list []string, item, mark string, after bool) (moved []string, ok bool) {
+	from := indexOf(list, item)
+	if from == -1 || indexOf(list, mark) == -1 {
+		return list, false
+	}
+	if item == mark {
+		return list, true
+	}
+	list = append(list[:from], list[from+1:]...)
+	to := indexOf(list, mark)
+	if after {
+		to++
+	}
+	list = append(list, "")
+	copy(list[to+1:], list[to:])
+	list[to] = item
+	return list, true
+}
+
+// Parse parses a specified configuration file and returns a Configuration instance.
 func Parse(filePath string) (*IniFile, error) {
-	filePath = path.Clean(filePath)
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+	return ParseOptions{}.Parse(filePath)
+}
+
+// ParseFS parses the configuration file name of fsys, such as an embed.FS, and returns
+// a Configuration instance. The returned configuration has no file path.
+func ParseFS(fsys fs.FS, name string) (*IniFile, error) {
+	return ParseOptions{}.ParseFS(fsys, name)
+}
+
+// ParseReader parses the configuration read from r and returns a Configuration instance.
+// The returned configuration has no file path.
+func ParseReader(r io.Reader) (*IniFile, error) {
+	return ParseOptions{}.ParseReader(r)
+}
+
+// ParseBytes parses the configuration contained in data.
+func ParseBytes(data []byte) (*IniFile, error) {
+	return ParseOptions{}.ParseBytes(data)
+}
+
+// ParseString parses the configuration contained in str.
+func ParseString(str string) (*IniFile, error) {
+	return ParseOptions{}.ParseString(str)
+}
+
+func (c *IniFile) AddSection(name string) *Section {
+	return c.AddSubsection(name, "")
+}
+
+// AddSubsection adds a new `[name "subName"]` section, as found in git configuration files.
+// Subsections are stored as sections named name and told apart by their SubName.
+// With the MergeSections policy the existing section is returned instead.
+func (c *IniFile) AddSubsection(name, subName string) *Section {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.Frozen() {
+		if section := c.subsection(name, subName); section != nil {
+			return section
+		}
+		return &Section{name: name, subName: subName, options: make(map[string]string)}
 	}
-	defer file.Close()
+	return c.addSubsection(name, subName)
+}
 
-	// New File
-	c := NewIniFile(filePath)
+// addSubsection adds a new `[name "subName"]` section. The caller must hold the lock.
+func (c *IniFile) addSubsection(name, subName string) *Section {
+	name = c.nameOf(name)
+	if c.sectionPolicy == MergeSections {
+		if section := c.subsection(name, subName); section != nil {
+			return section
+		}
+	}
 
-	activeSection := c.AddSection("global")
+	section := &Section{file: c, name: name, subName: subName, options: make(map[string]string)}
+	c.restructure()
+	if _, ok := c.sections[name]; !ok {
+		if section.global = name == c.globalSectionName() && subName == ""; section.global {
+			c.orderedSections = append([]string{name}, c.orderedSections...)
+		} else {
+			c.orderedSections = append(c.orderedSections, name)
+		}
+	}
+	c.sections[name] = append(c.sections[name], section)
+	return section
+}
+
+// Clone returns a deep copy of the configuration, including its sections, their
+// order, options and comments, and its settings.
+func (c *IniFile) Clone() *IniFile {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-	scanner := bufio.NewScanner(bufio.NewReader(file))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !(strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")) && len(line) > 0 {
-			if isSection(line) {
-				name := strings.Trim(line, " []")
-				activeSection = c.AddSection(name)
-				continue
-			} else {
-				activeSection.AddOption(line)
+	clone := NewIniFile(c.filePath)
+	clone.orderedSections = slices.Clone(c.orderedSections)
+	clone.saveOptions = c.saveOptions
+	clone.expandEnv = c.expandEnv
+	clone.useDefaults = c.useDefaults
+	clone.sectionPolicy = c.sectionPolicy
+	clone.pathSeparator = c.pathSeparator
+	clone.envPrefix, clone.envMapper = c.envPrefix, c.envMapper
+	clone.dialect = c.dialect
+	clone.newline, clone.encoding = c.newline, c.encoding
+	clone.parseOptions = c.parseOptions
+	clone.loaded, clone.loadedSum = c.loaded, c.loadedSum
+	clone.modified, clone.restructured = maps.Clone(c.modified), c.restructured
+	clone.aliases = c.aliases
+	clone.ignoreCase.Store(c.ignoreCase.Load())
+	clone.globalName = c.globalName
+	raws := make(map[*rawSection]*rawSection, len(c.raws)) // raws of the sections to those of their clones
+	for name, sections := range c.sections {
+		clones := make([]*Section, len(sections))
+		for i, s := range sections {
+			clones[i] = s.Clone()
+			clones[i].file = clone
+			clones[i].global = s.global
+			s.mutex.RLock()
+			if s.raw != nil {
+				raws[s.raw] = clones[i].raw
+			}
+			s.mutex.RUnlock()
+		}
+		clone.sections[name] = clones
+	}
+	if c.raws != nil {
+		clone.raws = make([]*rawSection, len(c.raws))
+		for i, r := range c.raws {
+			if clone.raws[i] = raws[r]; clone.raws[i] == nil {
+				clone.raws[i] = r.clone() // of a deleted section
 			}
-		} else {
-			// save comments
-			activeSection.AddOption(line)
 		}
 	}
+	return clone
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// SetExpandEnv enables or disables the expansion of environment variables in values.
+func (c *IniFile) SetExpandEnv(enabled bool) {
+	if c.Frozen() {
+		return
 	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	return c, nil
+	c.expandEnv = enabled
 }
 
-func (c *IniFile) AddSection(name string) *Section {
-	section := &Section{name:name, options : make(map[string]string)}
-	var lst *list.List
-	if lst = c.sections[name]; lst == nil {
-		lst = list.New()
-		c.sections[name] = lst
-		c.orderedSections = append(c.orderedSections, name)
+// ExpandsEnv returns true if environment variables in values are expanded when read.
+func (c *IniFile) ExpandsEnv() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.expandEnv
+}
+
+// DuplicateSectionPolicy returns the policy used for sections declared several times.
+func (c *IniFile) DuplicateSectionPolicy() DuplicateSectionPolicy {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.sectionPolicy
+}
+
+// SetUseDefaults enables or disables the fallback of options missing in a section
+// to the options of the DEFAULT section when values are read.
+func (c *IniFile) SetUseDefaults(enabled bool) {
+	if c.Frozen() {
+		return
 	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	lst.PushBack(section)
-	return section
+	c.useDefaults = enabled
+}
+
+// UsesDefaults returns true if options missing in a section fall back to the DEFAULT section.
+func (c *IniFile) UsesDefaults() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.useDefaults
 }
 
-// Save the Configuration to file. Creates a backup (.bak) if file already exists.
-func (c *IniFile) Save(filePath string) (err error) {
+// SetIgnoreCase makes section and option names match regardless of case when
+// looked up. Names keep the spelling they were added with.
+func (c *IniFile) SetIgnoreCase(enabled bool) {
+	if c.Frozen() {
+		return
+	}
+	c.ignoreCase.Store(enabled)
+}
+
+// IgnoresCase returns true if section and option names match regardless of case.
+func (c *IniFile) IgnoresCase() bool {
+	return c.ignoreCase.Load()
+}
+
+// nameOf returns the spelling of the sections named name, name itself if there
+// are none or case matters. The caller must hold the lock.
+func (c *IniFile) nameOf(name string) string {
+	if !c.ignoreCase.Load() {
+		return name
+	}
+	if _, ok := c.sections[name]; ok {
+		return name
+	}
+	for _, n := range c.orderedSections {
+		if strings.EqualFold(n, name) {
+			return n
+		}
+	}
+	return name
+}
+
+// GlobalSectionName returns the name of the global section, holding the options
+// preceding the first section header, see ParseOptions.GlobalSection.
+func (c *IniFile) GlobalSectionName() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.globalSectionName()
+}
+
+// globalSectionName returns the name of the global section. The caller must hold the lock.
+func (c *IniFile) globalSectionName() string {
+	if c.globalName == "" {
+		return "global"
+	}
+	return c.globalName
+}
+
+// globalSection returns the global section, or nil.
+func (c *IniFile) globalSection() *Section {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, s := range c.sections[c.globalSectionName()] {
+		if s.global {
+			return s
+		}
+	}
+	return nil
+}
+
+// SaveOptions controls how a configuration is written by Save and WriteTo.
+type SaveOptions struct {
+	// WrapColumn wraps option lines longer than WrapColumn characters using
+	// trailing-backslash continuation lines, read back unchanged with
+	// ParseOptions.AllowLineContinuation. Lines are only broken at spaces.
+	// Zero disables wrapping.
+	WrapColumn int
+
+	// Backup controls the backup made of an existing file when saving, a single .bak file by default.
+	Backup BackupPolicy
+
+	// Delimiter is written between option names and values, "=" if empty. Use ":"
+	// or " " for files parsed with the matching ParseOptions.Delimiters.
+	Delimiter string
+
+	// CommentPrefix starts the lines of comments set without comment character,
+	// "#" if empty, ";" for inline comments. Parsed comments keep their prefix.
+	CommentPrefix string
+
+	// EscapeValueDelims writes the '=' and ':' of unquoted values as "\=" and "\:",
+	// for readers splitting option lines on every delimiter. Values containing
+	// backslashes are quoted instead. See ParseOptions.EscapeValueDelims.
+	EscapeValueDelims bool
+	// IndentedContinuation writes the values holding several lines on indented
+	// continuation lines, rather than quoted with escape sequences, when they can be
+	// read back unchanged. It is set by ParseOptions.AllowIndentedContinuation.
+	IndentedContinuation bool
+
+	// QuoteInlineComments quotes the values holding a '#' or ';' at their start or
+	// after whitespace, which parsers splitting inline comments would cut. It is set
+	// by ParseOptions.AllowInlineComments.
+	QuoteInlineComments bool
+
+	// EscapeValues writes values with the escape sequences of Escape rather than
+	// between quotes, unless they start or end with spaces or quotes.
+	// See ParseOptions.EscapeValues.
+	EscapeValues bool
+
+	// SpaceAroundDelimiter writes options as "key = value" instead of "key=value".
+	SpaceAroundDelimiter bool
+
+	// AlignValues pads the option names of each section so that their values line up.
+	AlignValues bool
+
+	// BlankLineBetweenSections writes an empty line between sections.
+	BlankLineBetweenSections bool
+
+	// OmitEmptyValues skips the options with an empty value instead of writing "key=".
+	// Bare options, see Section.IsBare, are skipped too unless BareKeysAsBool is set.
+	OmitEmptyValues bool
+
+	// Newline is the line ending written, "\n" or "\r\n". If empty, the line ending
+	// of the parsed file is kept, see IniFile.Newline.
+	Newline string
+
+	// KeepEncoding makes Save write the file with the encoding and byte order mark
+	// of the parsed file, see IniFile.Encoding, instead of UTF-8 without byte order mark.
+	KeepEncoding bool
+
+	// FileMode is the permission bits of files created when saving, 0644 if zero.
+	// Existing files keep their mode, and their owner and group where permitted.
+	FileMode fs.FileMode
+
+	commentPrefixes []string // prefixes of the comments of the file, see IniFile.writeOptions
+}
+
+// commentPrefixList returns the prefixes of the comments of the file written, "#" and
+// ";" if not set.
+func (o SaveOptions) commentPrefixList() []string {
+	if o.commentPrefixes == nil {
+		return defaultCommentPrefixes
+	}
+	return o.commentPrefixes
+}
+
+// writeOptions returns the save options of the configuration along with the comment
+// prefixes it was parsed with. The caller must hold the lock.
+func (c *IniFile) writeOptions() SaveOptions {
+	opts := c.saveOptions
+	opts.commentPrefixes = c.parseOptions.commentPrefixes()
+	return opts
+}
+
+// SetSaveOptions sets the options used when writing the configuration.
+func (c *IniFile) SetSaveOptions(opts SaveOptions) {
 	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	err = os.Rename(filePath, filePath+".bak")
-	if err != nil {
-		if !os.IsNotExist(err) { // fine if the file does not exists
-			return err
+	c.saveOptions = opts
+}
+
+// Save writes the configuration back to FilePath. Creates a backup if the file already exists, see SaveOptions.Backup.
+func (c *IniFile) Save() error {
+	filePath := c.FilePath()
+	if filePath == "" {
+		return ErrNoFilePath
+	}
+	if !c.IsModified() {
+		c.mutex.RLock()
+		loaded := c.loaded
+		c.mutex.RUnlock()
+		if state, err := statFile(filePath); err == nil && state == loaded {
+			return nil // nothing to write
 		}
 	}
+	return c.SaveAs(filePath)
+}
 
-	f, err := os.Create(filePath)
-	if err != nil {
+// SaveAs writes the configuration to filePath, which becomes its FilePath.
+// Creates a backup if the file already exists, see SaveOptions.Backup. The
+// configuration is written to a temporary file in the same directory that is synced
+// and then renamed over filePath, so the file is never left partially written. An
+// existing file keeps its mode and, where permitted, its owner, see SaveOptions.FileMode.
+// The configuration is serialized first and not locked while the file is written.
+// Concurrent saves of the configuration are written one at a time.
+func (c *IniFile) SaveAs(filePath string) error {
+	c.saving.Lock()
+	defer c.saving.Unlock()
+
+	c.mutex.RLock()
+	version := c.version
+	c.mutex.RUnlock()
+	content := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(content)
+	content.Reset()
+	if _, err := c.WriteTo(content); err != nil {
 		return err
 	}
+	c.mutex.RLock()
+	data := content.Bytes()
+	if c.saveOptions.KeepEncoding {
+		data = encodeText(data, c.encoding)
+	}
+	c.mutex.RUnlock()
+	return c.writeFile(filePath, data, version)
+}
 
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
+// bufferPool holds the buffers configurations are serialized into when saved.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
 
-	defer w.Flush()
+// writeFile replaces the content of filePath with data as described by SaveAs. The
+// changes of the configuration are forgotten unless some were made after version,
+// the version data was serialized from.
+func (c *IniFile) writeFile(filePath string, data []byte, version uint64) error {
+	info, err := os.Stat(filePath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.mutex.RLock()
+	mode := c.saveOptions.FileMode.Perm()
+	c.mutex.RUnlock()
+	if mode == 0 {
+		mode = 0644
+	}
+	if exists {
+		mode = info.Mode().Perm()
+	}
 
-	c.mutex.Unlock()
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
 
-	s, err := c.Sections()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil && exists {
+		chownLike(tmp, info)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return err
 	}
 
+	var backupPath string
+	if exists {
+		c.mutex.RLock()
+		policy := c.saveOptions.Backup
+		c.mutex.RUnlock()
+		if backupPath, err = policy.backup(filePath); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(filePath))
+	state, _ := statFile(filePath)
+
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.filePath, c.backupPath = filePath, backupPath
+	c.loaded, c.loadedSum = state, sha256.Sum256(data)
+	if c.version == version {
+		c.modified, c.restructured = nil, false
+	}
+	c.mutex.Unlock()
+	return nil
+}
 
-	for _, v := range s {
-		w.WriteString(v.String())
+// syncDir flushes the directory entry changes of dir to disk where supported.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
 	}
+}
+
+// Newline returns the line ending of the parsed file, "\r\n" or "\n".
+func (c *IniFile) Newline() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-	return err
+	if c.newline == "" {
+		return "\n"
+	}
+	return c.newline
 }
 
+// WriteTo writes the text representation of the configuration to w. It implements io.WriterTo.
+func (c *IniFile) WriteTo(w io.Writer) (n int64, err error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	newline := c.saveOptions.Newline
+	if newline == "" {
+		newline = c.newline
+	}
+	if newline == "" {
+		newline = "\n"
+	}
+
+	for _, name := range c.orderedSections {
+		for _, s := range c.sections[name] {
+			text := c.formatSection(s)
+			if c.saveOptions.BlankLineBetweenSections && n > 0 && !s.global {
+				text = "\n" + text
+			}
+			if newline != "\n" {
+				text = strings.ReplaceAll(text, "\n", newline)
+			}
+			if n == 0 && strings.HasPrefix(text, "\ufeff") {
+				text = "\ufeff" + text // the byte order mark skipped when parsing
+			}
+			m, err := io.WriteString(w, text)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
 
-// FilePath returns the configuration file path.
+// FilePath returns the configuration file path, empty for configurations built in memory.
 func (c *IniFile) FilePath() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	return c.filePath
 }
 
+// SetFilePath sets the path of the file the configuration is saved to by Save.
+func (c *IniFile) SetFilePath(filePath string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.filePath = filePath
+}
+
 // StringValue returns the string value for the specified section and option.
+// The error wraps ErrSectionNotFound or ErrOptionNotFound if either does not exist.
 func (c *IniFile) StringValue(section, option string) (value string, err error) {
 	s, err := c.Section(section)
 	if err != nil {
 		return
 	}
-	value = s.ValueOf(option)
-	return
+	return s.value(option)
+}
+
+// StringValueSafe returns the string value for the specified section and option,
+// or an empty string if either of them does not exist.
+func (c *IniFile) StringValueSafe(section, option string) string {
+	value, _ := c.StringValueOk(section, option)
+	return value
+}
+
+// StringValueOk returns the string value for the specified section and option and
+// whether both of them exist.
+func (c *IniFile) StringValueOk(section, option string) (string, bool) {
+	s := c.section(section)
+	if s == nil {
+		return "", false
+	}
+	return s.ValueOk(option)
+}
+
+// HasSection returns true if a section with the fully qualified section name exists.
+func (c *IniFile) HasSection(name string) bool {
+	return c.section(name) != nil
+}
+
+// HasOption returns true if the option exists in the specified section.
+func (c *IniFile) HasOption(section, option string) bool {
+	s := c.section(section)
+	return s != nil && s.Exists(option)
+}
+
+// SetCommentFor sets the comment lines written before the option of the specified
+// section, see Section.SetCommentFor.
+func (c *IniFile) SetCommentFor(section, option, comment string) error {
+	s, err := c.Section(section)
+	if err != nil {
+		return err
+	}
+	s.SetCommentFor(option, comment)
+	return nil
+}
+
+// SetPathSeparator sets the separator between section and option names used by Get and Set,
+// "." by default.
+func (c *IniFile) SetPathSeparator(sep string) {
+	if c.Frozen() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pathSeparator = sep
+}
+
+// splitPath splits path at its last separator into a section and an option name.
+// Paths without separator address the global section.
+func (c *IniFile) splitPath(path string) (section, option string) {
+	c.mutex.RLock()
+	sep := c.pathSeparator
+	c.mutex.RUnlock()
+
+	if sep == "" {
+		sep = "."
+	}
+	if i := strings.LastIndex(path, sep); i != -1 {
+		return path[:i], path[i+len(sep):]
+	}
+	return c.GlobalSectionName(), path
+}
+
+// Get returns the value of the option addressed by path, a section name and an option name
+// joined by the path separator: "server.http.port" is the port option of the server.http section.
+func (c *IniFile) Get(path string) (string, error) {
+	section, option := c.splitPath(path)
+	return c.StringValue(section, option)
+}
+
+// Set sets the value of the option addressed by path, see Get, adding the option
+// and its section if they do not exist.
+func (c *IniFile) Set(path string, value string) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	section, option := c.splitPath(path)
+	c.sectionOrAdd(section).Add(option, value)
+	return nil
 }
 
 // Delete deletes the specified sections matched by a regex name and returns the deleted sections.
 func (c *IniFile) Delete(regex string) (sections []*Section, err error) {
-	sections, err = c.Find(regex)
+	if c.Frozen() {
+		return nil, ErrFrozen
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return c.DeleteRe(re), nil
+}
+
+// DeleteRe deletes the sections whose name matches re and returns the deleted sections.
+func (c *IniFile) DeleteRe(re *regexp.Regexp) []*Section {
+	if c.Frozen() {
+		return nil
+	}
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	if err == nil {
-		for _, s := range sections {
-			delete(c.sections, s.name)
+	sections := c.findRe(re)
+	for _, s := range sections {
+		delete(c.sections, s.name)
+	}
+	c.orderedSections = slices.DeleteFunc(c.orderedSections, re.MatchString)
+	if len(sections) > 0 {
+		c.restructure()
+	}
+	return sections
+}
+
+// DeleteSection deletes all the sections with the exact specified name.
+func (c *IniFile) DeleteSection(name string) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name = c.nameOf(name)
+	if _, ok := c.sections[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+	}
+	delete(c.sections, name)
+	c.restructure()
+	for i, n := range c.orderedSections {
+		if n == name {
+			c.orderedSections = append(c.orderedSections[:i], c.orderedSections[i+1:]...)
+			break
 		}
-		// remove also from ordered list
-		var matched bool
-		for i, name := range c.orderedSections {
-			if matched, err = regexp.MatchString(regex, name); matched {
-				c.orderedSections = append(c.orderedSections[:i], c.orderedSections[i+1:]...)
-			} else {
-				if err != nil {
-					return nil, err
-				}
+	}
+	return nil
+}
+
+// RenameSection renames all the sections named oldName to newName, keeping their position.
+// Sections inheriting from oldName are updated to inherit from newName.
+func (c *IniFile) RenameSection(oldName, newName string) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	oldName = c.nameOf(oldName)
+	if _, ok := c.sections[oldName]; !ok {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, oldName)
+	}
+	if existing := c.nameOf(newName); existing != oldName || newName == oldName {
+		if _, ok := c.sections[existing]; ok {
+			return fmt.Errorf("%w: %s", ErrSectionExists, newName)
+		}
+	}
+	c.sections[newName] = c.sections[oldName]
+	delete(c.sections, oldName)
+	c.restructure()
+	for i, n := range c.orderedSections {
+		if n == oldName {
+			c.orderedSections[i] = newName
+		}
+	}
+	for name, sections := range c.sections {
+		for _, s := range sections {
+			s.mutex.Lock()
+			if name == newName {
+				s.name, s.global = newName, false
+			}
+			if s.parent == oldName {
+				s.parent = newName
 			}
+			s.mutex.Unlock()
 		}
 	}
-	return sections, err
+	return nil
+}
+
+// MoveSectionBefore moves the sections named name just before the sections named mark.
+func (c *IniFile) MoveSectionBefore(name, mark string) error {
+	return c.moveSection(name, mark, false)
+}
+
+// MoveSectionAfter moves the sections named name just after the sections named mark.
+func (c *IniFile) MoveSectionAfter(name, mark string) error {
+	return c.moveSection(name, mark, true)
+}
+
+func (c *IniFile) moveSection(name, mark string, after bool) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name, mark = c.nameOf(name), c.nameOf(mark)
+	var ok bool
+	if c.orderedSections, ok = moveItem(c.orderedSections, name, mark, after); !ok {
+		if indexOf(c.orderedSections, name) == -1 {
+			return fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+		}
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, mark)
+	}
+	c.restructure()
+	return nil
+}
+
+// removeSection removes the section instance s.
+func (c *IniFile) removeSection(s *Section) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sections, ok := c.sections[s.name]
+	if !ok {
+		return
+	}
+	if i := slices.Index(sections, s); i != -1 {
+		sections = slices.Delete(sections, i, i+1)
+		c.sections[s.name] = sections
+		c.restructure()
+	}
+	if len(sections) == 0 {
+		delete(c.sections, s.name)
+		if i := indexOf(c.orderedSections, s.name); i != -1 {
+			c.orderedSections = append(c.orderedSections[:i], c.orderedSections[i+1:]...)
+		}
+	}
+}
+
+// DeleteOption deletes the option from all the sections with the exact specified name.
+func (c *IniFile) DeleteOption(section, option string) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	sections := c.SectionsByName(section)
+	if sections == nil {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, section)
+	}
+	found := false
+	for _, s := range sections {
+		if s.Exists(option) {
+			s.Delete(option)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrOptionNotFound, option)
+	}
+	return nil
 }
 
 // Section returns the first section matching the fully qualified section name.
 func (c *IniFile) Section(name string) (*Section, error) {
+	if s := c.section(name); s != nil {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+}
+
+// section returns the first section matching the fully qualified section name, or nil.
+func (c *IniFile) section(name string) *Section {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	if l, ok := c.sections[name]; ok {
-		for e := l.Front(); e != nil; e = e.Next() {
-			s := e.Value.(*Section)
-			return s, nil
-		}
+	if sections := c.sections[c.nameOf(name)]; len(sections) > 0 {
+		return sections[0]
 	}
-	return nil, errors.New("Unable to find " + name)
+	return nil
 }
 
+// sectionOrAdd returns the first section named name, adding it if there is none,
+// without releasing the lock in between so that concurrent calls add one section.
+func (c *IniFile) sectionOrAdd(name string) *Section {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if sections := c.sections[c.nameOf(name)]; len(sections) > 0 {
+		return sections[0]
+	}
+	return c.addSubsection(name, "")
+}
 
-// Sections returns a slice of Sections matching the fully qualified section name.
-func (c *IniFile) Sections(name string) ([]*Section, error) {
+// Subsection returns the first `[name "subName"]` section.
+func (c *IniFile) Subsection(name, subName string) (*Section, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	var sections []*Section
+	if s := c.subsection(name, subName); s != nil {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: %s \"%s\"", ErrSectionNotFound, name, subName)
+}
 
-	f := func(lst *list.List) {
-		for e := lst.Front(); e != nil; e = e.Next() {
-			s := e.Value.(*Section)
-			sections = append(sections, s)
+// subsection returns the first `[name "subName"]` section, or nil. The caller must hold the lock.
+func (c *IniFile) subsection(name, subName string) *Section {
+	for _, s := range c.sections[c.nameOf(name)] {
+		if s.SubName() == subName {
+			return s
 		}
 	}
+	return nil
+}
 
-	if name == "" {
-		// Get all sections.
-		for _, name := range c.orderedSections {
-			if lst, ok := c.sections[name]; ok {
-				f(lst)
+// Sections returns all the sections in the order they were added.
+func (c *IniFile) Sections() []*Section {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	n := 0
+	for _, sections := range c.sections {
+		n += len(sections)
+	}
+	sections := make([]*Section, 0, n)
+	for _, name := range c.orderedSections {
+		sections = append(sections, c.sections[name]...)
+	}
+	return sections
+}
+
+// All returns an iterator over all the sections in the order they were added.
+// The sections are collected when the iteration starts, see Sections.
+func (c *IniFile) All() iter.Seq[*Section] {
+	return func(yield func(*Section) bool) {
+		for _, s := range c.Sections() {
+			if !yield(s) {
+				return
 			}
 		}
-	} else {
-		if lst, ok := c.sections[name]; ok {
-			f(lst)
-		} else {
-			return nil, errors.New("Unable to find " + name)
-		}
 	}
+}
+
+// sectionCount returns the number of sections, the global one included.
+func (c *IniFile) sectionCount() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	n := 0
+	for _, sections := range c.sections {
+		n += len(sections)
+	}
+	return n
+}
+
+// SectionsByName returns all the sections matching the fully qualified section name,
+// more than one if the section was declared several times.
+func (c *IniFile) SectionsByName(name string) []*Section {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.sectionsByName(name)
+}
 
+// sectionsByName returns the sections named name. The caller must hold the lock.
+func (c *IniFile) sectionsByName(name string) []*Section {
+	return slices.Clone(c.sections[c.nameOf(name)])
+}
+
+// SectionNames returns the distinct section names in the order they were added.
+func (c *IniFile) SectionNames() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]string(nil), c.orderedSections...)
+}
+
+// SectionsOf returns a slice of Sections matching the fully qualified section name,
+// or all sections if name is empty.
+//
+// Deprecated: SectionsOf has the behavior of the former Sections(name string);
+// use Sections or SectionsByName instead.
+func (c *IniFile) SectionsOf(name string) ([]*Section, error) {
+	if name == "" {
+		return c.Sections(), nil
+	}
+	sections := c.SectionsByName(name)
+	if sections == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+	}
 	return sections, nil
 }
 
 // Find returns a slice of Sections matching the regexp against the section name.
 func (c *IniFile) Find(regex string) ([]*Section, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindRe(re), nil
+}
+
+// FindRe returns the sections whose name matches re, in the order they were added.
+func (c *IniFile) FindRe(re *regexp.Regexp) []*Section {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
+	return c.findRe(re)
+}
+
+// findRe returns the sections whose name matches re. The caller must hold the lock.
+func (c *IniFile) findRe(re *regexp.Regexp) []*Section {
 	var sections []*Section
-	for key, lst := range c.sections {
-		if matched, err := regexp.MatchString(regex, key); matched {
-			for e := lst.Front(); e != nil; e = e.Next() {
-				s := e.Value.(*Section)
-				sections = append(sections, s)
-			}
-		} else {
-			if err != nil {
-				return nil, err
-			}
+	for _, name := range c.orderedSections {
+		if re.MatchString(name) {
+			sections = append(sections, c.sections[name]...)
 		}
 	}
-	return sections, nil
+	return sections
 }
 
 // PrintSection prints a text representation of all sections matching the fully qualified section name.
 func (c *IniFile) PrintSection(name string) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
-	sections, err := c.Sections(name)
+	sections, err := c.SectionsOf(name)
 	if err == nil {
 		for _, section := range sections {
 			fmt.Print(section)
 		}
 	} else {
-		fmt.Printf("Unable to find section %v\n", err)
+		fmt.Printf("Unable to find section %s\n", name)
 	}
 }
 
 // String returns the text representation of a parsed configuration file.
 func (c *IniFile) String() string {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	var b strings.Builder
+	b.Grow(c.sizeHint())
+	c.WriteTo(&b)
+	return b.String()
+}
 
-	var parts []string
-	for _, name := range c.orderedSections {
-		sections, _ := c.Sections(name)
-		for _, section := range sections {
-			parts = append(parts, section.String())
-		}
+// sizeHint returns an estimate of the length of the text representation of the configuration.
+func (c *IniFile) sizeHint() int {
+	n := 0
+	for _, s := range c.Sections() {
+		s.mutex.RLock()
+		n += s.sizeHint()
+		s.mutex.RUnlock()
 	}
-	return strings.Join(parts, "")
+	return n
 }
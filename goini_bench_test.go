@@ -0,0 +1,87 @@
+package goini
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// benchConfig returns a configuration of 20 sections of 20 options each.
+func benchConfig() string {
+	var b strings.Builder
+	b.WriteString("; benchmark configuration\n")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&b, "[section%d]\n", i)
+		for j := 0; j < 20; j++ {
+			fmt.Fprintf(&b, "option%d = value %d of section %d\n", j, j, i)
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkParse(b *testing.B) {
+	data := []byte(benchConfig())
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseBytes(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValueOf(b *testing.B) {
+	c, err := ParseString(benchConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := c.section("section10")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.ValueOf("option10")
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	c, err := ParseString(benchConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = c.String()
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	c, err := ParseString(benchConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	filePath := filepath.Join(b.TempDir(), "bench.ini")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := c.SaveAs(filePath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetterAllocations(t *testing.T) {
+	c, err := ParseString(benchConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("section10")
+	getters := map[string]func(){
+		"StringValue":   func() { c.StringValue("section10", "option10") },
+		"StringValueOk": func() { c.StringValueOk("section10", "option10") },
+		"ValueOf":       func() { s.ValueOf("option10") },
+	}
+	for name, get := range getters {
+		if allocs := testing.AllocsPerRun(100, get); allocs != 0 {
+			t.Errorf("%s allocates %v times per call, want 0", name, allocs)
+		}
+	}
+}
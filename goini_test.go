@@ -0,0 +1,101 @@
+package goini
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSaveAndMutation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "concurrent.ini")
+	c, err := ParseString("[server]\nhost = localhost\nport = 8080\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveAs(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, rounds = 4, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 5*workers*rounds)
+	for w := 0; w < workers; w++ {
+		wg.Add(5)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := c.Save(); err != nil {
+					errs <- fmt.Errorf("Save: %w", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := c.Set(fmt.Sprintf("server.key%d", w), fmt.Sprint(i)); err != nil {
+					errs <- fmt.Errorf("Set: %w", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				c.AddSection(fmt.Sprintf("tmp%d", w)).Add("round", fmt.Sprint(i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if _, err := c.Delete(fmt.Sprintf("^tmp%d$", w)); err != nil {
+					errs <- fmt.Errorf("Delete: %w", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if _, err := Parse(filePath); err != nil {
+					errs <- fmt.Errorf("Parse of the saved file: %w", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := saved.String(), c.String(); got != want {
+		t.Errorf("saved file is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConcurrentSetAddsOneSection(t *testing.T) {
+	c := NewIniFile("")
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Set(fmt.Sprintf("new.key%d", w), "value"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sections := c.Sections(); len(sections) != 1 {
+		t.Fatalf("%d sections added, want 1", len(sections))
+	}
+	if options := c.Sections()[0].OptionNames(); len(options) != 8 {
+		t.Errorf("options are %q, want 8 options", options)
+	}
+}
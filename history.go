@@ -0,0 +1,143 @@
+package goini
+
+import "errors"
+
+// ErrNoHistory is returned by Undo and Redo when there is nothing to undo or redo.
+var ErrNoHistory = errors.New("nothing to undo or redo")
+
+// SetHistoryLimit keeps up to limit changes of the values of the configuration so
+// that they can be undone, see Undo. A limit of zero, the default, disables the
+// history and forgets the changes kept.
+//
+// Changes are recorded when options are set, added, deleted or renamed, a committed
+// transaction being a single change. Undoing restores the values; the comments of
+// a deleted option are lost and an option restored is appended to its section, while
+// a renamed option is renamed back with its values and comments.
+// The history is forgotten when the configuration is reloaded.
+func (c *IniFile) SetHistoryLimit(limit int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.historyLimit = max(limit, 0)
+	if len(c.history) > c.historyLimit {
+		c.history = c.history[len(c.history)-c.historyLimit:]
+	}
+	if c.historyLimit == 0 {
+		c.history, c.redo = nil, nil
+	}
+}
+
+// CanUndo returns true if there is a change to undo.
+func (c *IniFile) CanUndo() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return len(c.history) > 0
+}
+
+// CanRedo returns true if there is an undone change to redo.
+func (c *IniFile) CanRedo() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return len(c.redo) > 0
+}
+
+// Undo reverts the last change kept in the history, see SetHistoryLimit.
+func (c *IniFile) Undo() error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	c.mutex.Lock()
+	if len(c.history) == 0 {
+		c.mutex.Unlock()
+		return ErrNoHistory
+	}
+	e := c.history[len(c.history)-1]
+	c.history = c.history[:len(c.history)-1]
+	c.redo = append(c.redo, e)
+	c.mutex.Unlock()
+
+	tx := c.Begin()
+	if e.rename {
+		tx.rename(e.changes[1], e.changes[0].Option)
+	} else {
+		for i := len(e.changes) - 1; i >= 0; i-- {
+			change := e.changes[i]
+			tx.restore(change, change.OldValue, change.Existed)
+		}
+	}
+	tx.apply()
+	return nil
+}
+
+// Redo applies again the last change undone, as long as no other change was made since.
+func (c *IniFile) Redo() error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	c.mutex.Lock()
+	if len(c.redo) == 0 {
+		c.mutex.Unlock()
+		return ErrNoHistory
+	}
+	e := c.redo[len(c.redo)-1]
+	c.redo = c.redo[:len(c.redo)-1]
+	c.history = append(c.history, e)
+	c.mutex.Unlock()
+
+	tx := c.Begin()
+	if e.rename {
+		tx.rename(e.changes[0], e.changes[1].Option)
+	} else {
+		for _, change := range e.changes {
+			tx.restore(change, change.NewValue, change.Exists)
+		}
+	}
+	tx.apply()
+	return nil
+}
+
+// restore records setting the option of change to value if exists is true, deleting
+// it otherwise, in the first section of the name and subsection name of change.
+func (tx *Tx) restore(change ValueChange, value string, exists bool) {
+	kind := txDelete
+	if exists {
+		kind = txSet
+	}
+	tx.ops = append(tx.ops, txOp{kind: kind, section: change.Section, subName: change.SubName, exact: true,
+		option: change.Option, value: value})
+}
+
+// rename records renaming the option of change to newName in the first section of
+// the name and subsection name of change.
+func (tx *Tx) rename(change ValueChange, newName string) {
+	tx.ops = append(tx.ops, txOp{kind: txRename, section: change.Section, subName: change.SubName, exact: true,
+		option: change.Option, value: newName})
+}
+
+// edit is a change kept in the history: the changes of values made at once, or the
+// renaming of an option, undone by renaming it back so that its values and comments
+// are kept.
+type edit struct {
+	changes []ValueChange
+	rename  bool // changes are the removal of the old name and the addition of the new one
+}
+
+// record adds e to the history if it is enabled, and forgets the changes undone.
+func (c *IniFile) record(e edit) {
+	if len(e.changes) == 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.historyLimit == 0 {
+		return
+	}
+	c.history = append(c.history, e)
+	if len(c.history) > c.historyLimit {
+		c.history = c.history[len(c.history)-c.historyLimit:]
+	}
+	c.redo = nil
+}
@@ -0,0 +1,81 @@
+package goini
+
+import "testing"
+
+func TestUndoRedo(t *testing.T) {
+	c, err := ParseString("[server]\nhost = localhost\nport = 8080\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHistoryLimit(10)
+	s := c.section("server")
+	s.SetValueFor("port", "9090")
+	s.Delete("host")
+	if err := s.Rename("port", "listen"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := c.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.String(), "[server]\nport=8080\nhost=localhost\n"; got != want {
+		t.Errorf("after undoing everything:\n%s\nwant\n%s", got, want)
+	}
+	if err := c.Undo(); err != ErrNoHistory {
+		t.Errorf("Undo() = %v with nothing to undo, want ErrNoHistory", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := c.Redo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.String(), "[server]\nlisten=9090\n"; got != want {
+		t.Errorf("after redoing everything:\n%s\nwant\n%s", got, want)
+	}
+	if c.CanRedo() {
+		t.Error("CanRedo() = true with nothing to redo")
+	}
+}
+
+func TestUndoRedoSubsections(t *testing.T) {
+	c, err := ParseString(remotesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHistoryLimit(10)
+	a, b := subsection(t, c, "remote", "a"), subsection(t, c, "remote", "b")
+
+	b.SetValueFor("url", "https://b2.example")
+	if err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.ValueOf("url"); got != "https://a.example" {
+		t.Errorf("undo changed [remote \"a\"] url to %q", got)
+	}
+	if got := b.ValueOf("url"); got != "https://b.example" {
+		t.Errorf("[remote \"b\"] url is %q after undo, want https://b.example", got)
+	}
+
+	if err := c.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.ValueOf("url"); got != "https://a.example" {
+		t.Errorf("redo changed [remote \"a\"] url to %q", got)
+	}
+	if got := b.ValueOf("url"); got != "https://b2.example" {
+		t.Errorf("[remote \"b\"] url is %q after redo, want https://b2.example", got)
+	}
+
+	if err := b.Rename("url", "pushurl"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Exists("url") || b.Exists("pushurl") || a.Exists("pushurl") {
+		t.Errorf("undoing the rename left [remote \"a\"] %q and [remote \"b\"] %q", a.OptionNames(), b.OptionNames())
+	}
+}
@@ -0,0 +1,236 @@
+package goini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Errors returned by SaveInPlace.
+var (
+	ErrNotPreserved = errors.New("configuration was not parsed with PreserveFormatting")
+	ErrFileChanged  = errors.New("file changed since it was parsed")
+)
+
+// lineEdit replaces lines of a file.
+type lineEdit struct {
+	start, end int      // 0-based range of the replaced lines
+	lines      []string // new lines, without line endings
+}
+
+// SaveInPlace writes the changes made to a configuration parsed from FilePath with
+// ParseOptions.PreserveFormatting back to the file. Only the lines of the options
+// and sections that changed are rewritten, using the positions recorded when parsing;
+// the other lines are left as they are. The file is written as described by SaveAs.
+// ErrFileChanged is returned if the file no longer matches what was parsed or last saved.
+func (c *IniFile) SaveInPlace() error {
+	c.saving.Lock()
+	defer c.saving.Unlock()
+
+	c.mutex.RLock()
+	filePath, o, raws, newline, version := c.filePath, c.parseOptions, c.raws, c.newline, c.version
+	opts, dialect, encoding := c.writeOptions(), c.dialect, c.encoding
+	c.mutex.RUnlock()
+	if filePath == "" {
+		return ErrNoFilePath
+	}
+	if !o.PreserveFormatting || o.Dialect == DialectProperties || o.Charset != "" || encoding > EncodingUTF8BOM {
+		return ErrNotPreserved
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var bom []byte
+	if bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}) {
+		bom, data = data[:3], data[3:]
+	}
+	lines := strings.Split(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if err := checkLines(lines, raws); err != nil {
+		return err
+	}
+
+	edits, appended := c.inPlaceEdits(raws, opts, dialect)
+	slices.SortStableFunc(edits, func(a, b lineEdit) int {
+		if a.start != b.start {
+			return b.start - a.start
+		}
+		return b.end - a.end
+	})
+	cr := ""
+	if newline == "\r\n" {
+		cr = "\r"
+	}
+	for _, edit := range edits {
+		replaced := make([]string, len(edit.lines))
+		for i, line := range edit.lines {
+			replaced[i] = line + cr
+		}
+		lines = slices.Replace(lines, edit.start, edit.end, replaced...)
+	}
+	for _, line := range appended {
+		lines = append(lines, line+cr)
+	}
+
+	var content bytes.Buffer
+	content.Write(bom)
+	for _, line := range lines {
+		content.WriteString(line + "\n")
+	}
+	if err := c.writeFile(filePath, content.Bytes(), version); err != nil {
+		return err
+	}
+	return c.refreshRaws(content.Bytes(), filePath, o)
+}
+
+// checkLines returns ErrFileChanged if lines differ from the recorded lines of the file.
+func checkLines(lines []string, raws []*rawSection) error {
+	n := 0
+	check := func(line int, text string) error {
+		for i, physical := range strings.Split(text, "\n") {
+			number := line + i
+			if number > len(lines) || strings.TrimSuffix(lines[number-1], "\r") != physical {
+				return fmt.Errorf("%w: line %d", ErrFileChanged, number)
+			}
+			n++
+		}
+		return nil
+	}
+	for _, r := range raws {
+		for _, line := range slices.Concat(r.pre, r.lines) {
+			if line.hidden {
+				continue
+			}
+			if err := check(line.line, line.text); err != nil {
+				return err
+			}
+		}
+		if r.header != "" {
+			if err := check(r.headerLine, r.header); err != nil {
+				return err
+			}
+		}
+	}
+	if n != len(lines) {
+		return fmt.Errorf("%w: %d lines instead of %d", ErrFileChanged, len(lines), n)
+	}
+	return nil
+}
+
+// inPlaceEdits returns the edits writing the changes of the configuration to its
+// parsed lines and the lines of the sections to append to the file.
+func (c *IniFile) inPlaceEdits(raws []*rawSection, opts SaveOptions, dialect Dialect) (edits []lineEdit, appended []string) {
+	remove := func(line int, text string) {
+		edits = append(edits, lineEdit{start: line - 1, end: line - 1 + strings.Count(text, "\n") + 1})
+	}
+
+	present := make(map[*rawSection]bool)
+	for _, s := range c.Sections() {
+		s.mutex.RLock()
+		r := s.raw
+		if r == nil {
+			if text := s.format(opts, dialect); text != "" {
+				s.mutex.RUnlock()
+				appended = append(appended, strings.Split(strings.TrimSuffix(text, "\n"), "\n")...)
+				continue
+			}
+			s.mutex.RUnlock()
+			continue
+		}
+		present[r] = true
+
+		plan := s.plan(opts, dialect)
+		if r.header != "" && s.headerChanged() {
+			edits = append(edits, lineEdit{start: r.headerLine - 1, end: r.headerLine, lines: []string{s.header()}})
+		}
+		var added []string
+		for i, line := range r.lines {
+			text := plan.texts[i]
+			switch {
+			case line.hidden && text != nil:
+				added = append(added, *text)
+			case line.hidden:
+			case text == nil:
+				remove(line.line, line.text)
+			case *text != line.text:
+				edits = append(edits, lineEdit{start: line.line - 1, end: line.line + strings.Count(line.text, "\n"),
+					lines: strings.Split(*text, "\n")})
+			}
+		}
+		added = append(added, plan.added...)
+		if len(added) > 0 {
+			// after the last line of the file preceding the added lines, or the header
+			at := -1
+			for i := plan.at - 1; i >= 0 && at == -1; i-- {
+				if line := r.lines[i]; !line.hidden {
+					at = line.line + strings.Count(line.text, "\n")
+				}
+			}
+			switch {
+			case at == -1 && r.header != "":
+				at = r.headerLine
+			case at == -1 && s.global:
+				at = 0
+			case at == -1:
+				added = append([]string{s.header()}, added...)
+			}
+			if at == -1 {
+				appended = append(appended, added...)
+			} else {
+				edits = append(edits, lineEdit{start: at, end: at, lines: added})
+			}
+		}
+		s.mutex.RUnlock()
+	}
+
+	for _, r := range raws {
+		if present[r] {
+			continue
+		}
+		for _, line := range slices.Concat(r.pre, r.lines) {
+			if !line.hidden {
+				remove(line.line, line.text)
+			}
+		}
+		if r.header != "" {
+			remove(r.headerLine, r.header)
+		}
+	}
+	return edits, appended
+}
+
+// refreshRaws replaces the recorded lines of the sections with those of data, the
+// content of the file just saved.
+func (c *IniFile) refreshRaws(data []byte, filePath string, o ParseOptions) error {
+	saved, err := o.parse(bytes.NewReader(data), filePath, nil)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string][]*Section)
+	for _, s := range saved.Sections() {
+		key := s.name + "\x00" + s.subName
+		byKey[key] = append(byKey[key], s)
+	}
+	for _, s := range c.Sections() {
+		s.mutex.Lock()
+		key := s.name + "\x00" + s.subName
+		if matches := byKey[key]; len(matches) > 0 {
+			s.raw, byKey[key] = matches[0].raw, matches[1:]
+		} else {
+			s.raw = nil
+		}
+		s.mutex.Unlock()
+	}
+
+	c.mutex.Lock()
+	c.raws = saved.raws
+	c.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,124 @@
+package goini
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const inPlaceConfig = "# application settings\n" +
+	"[server]\n" +
+	"host   =  localhost   ; inline\n" +
+	"port = 8080\n" +
+	"\n" +
+	"[client]\n" +
+	"  timeout: 30\n"
+
+// parsePreserved writes text to a file and parses it with PreserveFormatting.
+func parsePreserved(t *testing.T, text string) (*IniFile, string) {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "inplace.ini")
+	if err := os.WriteFile(filePath, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseOptions{PreserveFormatting: true, AllowInlineComments: true}.Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, filePath
+}
+
+// readFile returns the content of filePath.
+func readFile(t *testing.T, filePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSaveInPlaceRewritesChangedLines(t *testing.T) {
+	c, filePath := parsePreserved(t, inPlaceConfig)
+	c.section("server").SetValueFor("port", "9090")
+	c.section("client").Add("retries", "3")
+	if err := c.SaveInPlace(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# application settings\n" +
+		"[server]\n" +
+		"host   =  localhost   ; inline\n" +
+		"port = 9090\n" +
+		"\n" +
+		"[client]\n" +
+		"  timeout: 30\n" +
+		"retries=3\n"
+	if got := readFile(t, filePath); got != want {
+		t.Errorf("file is\n%q\nwant\n%q", got, want)
+	}
+
+	// the recorded lines follow the saved file
+	c.section("server").SetValueFor("host", "example.com")
+	if err := c.SaveInPlace(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, filePath), "# application settings\n[server]\nhost   =  example.com   ; inline\n"; got[:len(want)] != want {
+		t.Errorf("file starts with\n%q\nwant\n%q", got[:len(want)], want)
+	}
+}
+
+func TestSaveInPlaceDeletedSection(t *testing.T) {
+	c, filePath := parsePreserved(t, inPlaceConfig)
+	if err := c.DeleteSection("client"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveInPlace(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# application settings\n[server]\nhost   =  localhost   ; inline\nport = 8080\n"
+	if got := readFile(t, filePath); got != want {
+		t.Errorf("file is\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSaveInPlaceClone(t *testing.T) {
+	c, filePath := parsePreserved(t, inPlaceConfig)
+	clone := c.Clone()
+	clone.section("server").SetValueFor("port", "9090")
+	if err := clone.SaveInPlace(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# application settings\n[server]\nhost   =  localhost   ; inline\nport = 9090\n\n[client]\n  timeout: 30\n"
+	if got := readFile(t, filePath); got != want {
+		t.Errorf("file is\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSaveInPlaceFileChanged(t *testing.T) {
+	c, filePath := parsePreserved(t, inPlaceConfig)
+	if err := os.WriteFile(filePath, []byte(inPlaceConfig+"extra = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.section("server").SetValueFor("port", "9090")
+	if err := c.SaveInPlace(); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("SaveInPlace() = %v, want ErrFileChanged", err)
+	}
+	if got := readFile(t, filePath); got != inPlaceConfig+"extra = 1\n" {
+		t.Errorf("file was rewritten to\n%q", got)
+	}
+}
+
+func TestSaveInPlaceNotPreserved(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plain.ini")
+	if err := os.WriteFile(filePath, []byte(inPlaceConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveInPlace(); !errors.Is(err, ErrNotPreserved) {
+		t.Errorf("SaveInPlace() = %v, want ErrNotPreserved", err)
+	}
+}
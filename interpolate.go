@@ -0,0 +1,175 @@
+package goini
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxInterpolationDepth limits the nesting of references resolved by ValueOfExpanded.
+const maxInterpolationDepth = 32
+
+// ValueOfExpanded returns the value of the specified option with ${key} and
+// ${section:key} references replaced by the values they refer to, recursively.
+// References without a section are resolved in s. "$$" is replaced by "$".
+// An error is returned for unknown references, reference cycles and nesting
+// deeper than 32 levels.
+//
+// If environment variables are expanded, see IniFile.SetExpandEnv, they are
+// expanded first: a ${name} reference to a defined environment variable is
+// replaced by the variable, not by the option name.
+func (s *Section) ValueOfExpanded(option string) (string, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return "", err
+	}
+	return s.expand(value, map[string]bool{s.Name() + ":" + option: true}, 0)
+}
+
+// expand replaces the references in value. visiting holds the references being
+// resolved to detect cycles.
+func (s *Section) expand(value string, visiting map[string]bool, depth int) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	if depth >= maxInterpolationDepth {
+		return "", fmt.Errorf("interpolation too deep in %q", value)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			b.WriteByte('$')
+			continue
+		}
+		end := strings.IndexByte(value[i:], '}')
+		if end == -1 {
+			return "", fmt.Errorf("unterminated reference in %q", value)
+		}
+		ref := value[i+2 : i+end]
+		i += end
+
+		section, option := s, ref
+		if j := strings.LastIndex(ref, ":"); j != -1 {
+			if s.file == nil {
+				return "", fmt.Errorf("unable to resolve %s outside of a configuration", ref)
+			}
+			var err error
+			if section, err = s.file.Section(ref[:j]); err != nil {
+				return "", err
+			}
+			option = ref[j+1:]
+		}
+		key := section.Name() + ":" + option
+		if visiting[key] {
+			return "", fmt.Errorf("interpolation cycle at %s", key)
+		}
+		resolved, err := section.value(option)
+		if err != nil {
+			return "", err
+		}
+		visiting[key] = true
+		if resolved, err = section.expand(resolved, visiting, depth+1); err != nil {
+			return "", err
+		}
+		delete(visiting, key)
+		b.WriteString(resolved)
+	}
+	return b.String(), nil
+}
+
+// expandEnv replaces the ${NAME} and %NAME% references to defined environment
+// variables in value. References to undefined variables are left untouched.
+func expandEnv(value string) string {
+	if !strings.ContainsAny(value, "$%") {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		var name string
+		var end int
+		switch {
+		case strings.HasPrefix(value[i:], "${"):
+			if j := strings.IndexByte(value[i+2:], '}'); j != -1 {
+				name, end = value[i+2:i+2+j], i+2+j
+			}
+		case value[i] == '%':
+			if j := strings.IndexByte(value[i+1:], '%'); j != -1 {
+				name, end = value[i+1:i+1+j], i+1+j
+			}
+		}
+		if name != "" {
+			if env, ok := os.LookupEnv(name); ok {
+				b.WriteString(env)
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// EnvMapper returns the name of the environment variable overriding an option, see
+// IniFile.SetEnvOverride. section is empty for the options of the global section.
+type EnvMapper func(prefix, section, option string) string
+
+// DefaultEnvMapper joins prefix, section and option with underscores, upper-cased and with
+// other characters than letters and digits replaced by underscores: the host option of the
+// [db] section with the APP prefix maps to APP_DB_HOST. The global section is omitted.
+func DefaultEnvMapper(prefix, section, option string) string {
+	parts := []string{prefix, section, option}
+	if section == "" {
+		parts = []string{prefix, option}
+	}
+	if prefix == "" {
+		parts = parts[1:]
+	}
+	name := []rune(strings.ToUpper(strings.Join(parts, "_")))
+	for i, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// SetEnvOverride makes environment variables override the values of options when they
+// are read. The name of the variable is computed by mapper, DefaultEnvMapper if nil,
+// from prefix and the section and option names. An empty prefix with a nil mapper
+// disables the overrides.
+func (c *IniFile) SetEnvOverride(prefix string, mapper EnvMapper) {
+	if c.Frozen() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.envPrefix, c.envMapper = prefix, mapper
+}
+
+// envOverride returns the value of the environment variable overriding the option, if any.
+func (c *IniFile) envOverride(section, option string) (string, bool) {
+	c.mutex.RLock()
+	prefix, mapper := c.envPrefix, c.envMapper
+	c.mutex.RUnlock()
+
+	if prefix == "" && mapper == nil {
+		return "", false
+	}
+	if mapper == nil {
+		mapper = DefaultEnvMapper
+	}
+	return os.LookupEnv(mapper(prefix, section, option))
+}
@@ -0,0 +1,179 @@
+package goini
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sectionHeaderText returns the text between the brackets of the header of s.
+func sectionHeaderText(s *Section) string {
+	h := header(s.name, s.subName)
+	if s.subName == "" && s.parent != "" {
+		h = s.header()
+	}
+	return h[1 : len(h)-1]
+}
+
+// jsonKey returns the member name of s in the object written by ToJSON: the text
+// between the brackets of its header, or an empty string for the global section.
+func jsonKey(s *Section) string {
+	if s.global {
+		return ""
+	}
+	return sectionHeaderText(s)
+}
+
+// ToJSON returns the configuration as a JSON object with a member per section, itself
+// an object with a string member per option, in file order. Options with shadow values
+// are written as arrays of strings. Of a section declared several times, only the first
+// declaration is written, the one returned by Section. The global section, whatever
+// its name, is the member with an empty name, and is omitted if it has no options; a
+// section with an empty name is written as `""`.
+func (c *IniFile) ToJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	written := make(map[string]bool)
+	for _, s := range c.Sections() {
+		key := jsonKey(s)
+		if written[key] || key == "" && len(s.OptionNames()) == 0 {
+			continue
+		}
+		written[key] = true
+		if len(written) > 1 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSONString(&buf, key); err != nil {
+			return nil, err
+		}
+		buf.WriteString(":{")
+
+		for i, opt := range s.OptionNames() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONString(&buf, opt); err != nil {
+				return nil, err
+			}
+			buf.WriteByte(':')
+			values := s.ValuesOf(opt)
+			var value interface{} = values[0]
+			if len(values) > 1 {
+				value = values
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// FromJSON builds a configuration from a JSON object as written by ToJSON. Member names
+// are parsed as section headers, the empty name standing for the global section, and
+// member order is preserved. Option values must be strings or arrays of strings;
+// numbers and booleans are accepted as their JSON text.
+func FromJSON(data []byte) (*IniFile, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	c := NewIniFile("")
+	global := c.AddSection(c.GlobalSectionName())
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a section name in JSON, got %v", token)
+		}
+		s := global
+		if key != "" {
+			name, subName, parent := ParseHeaderText(key)
+			s = c.AddSubsection(name, subName)
+			s.parent = parent
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			opt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			values, err := jsonValues(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s in section %q: %v", opt, key, err)
+			}
+			s.setValues(opt.(string), values)
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// expectDelim reads the next token of dec and checks that it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v in JSON, got %v", delim, t)
+	}
+	return nil
+}
+
+// jsonValues returns the values of an option from a JSON string, number, boolean or array of those.
+func jsonValues(raw json.RawMessage) ([]string, error) {
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		values = []json.RawMessage{raw}
+	}
+	if len(values) == 0 {
+		return nil, errors.New("empty array")
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			result[i] = s
+			continue
+		}
+		text := strings.TrimSpace(string(v))
+		if text == "" || text[0] == '{' || text[0] == '[' || text == "null" {
+			return nil, fmt.Errorf("unsupported value %s", text)
+		}
+		result[i] = text
+	}
+	return result, nil
+}
@@ -0,0 +1,167 @@
+package goini
+
+import "time"
+
+// Key is a handle on an option of a section, allowing chained conversions like
+// section.Key("port").MustInt(8080). The option does not need to exist.
+type Key struct {
+	section     *Section // nil if returned by IniFile.Key, see sec
+	file        *IniFile
+	sectionName string
+	name        string
+}
+
+// Key returns a handle on the specified option.
+func (s *Section) Key(name string) *Key {
+	return &Key{section: s, name: name}
+}
+
+// Key returns a handle on the option name of the first section with the fully
+// qualified name section, allowing chained conversions like
+//
+//	port := cfg.Key("http", "port").MustInt(8080)
+//
+// Neither the section nor the option need to exist: the section is looked up on each
+// use, and added by SetValue if it does not exist.
+func (c *IniFile) Key(section, name string) *Key {
+	return &Key{file: c, sectionName: section, name: name}
+}
+
+// sec returns the section of the option, an empty section that is not part of the
+// configuration if it does not exist.
+func (k *Key) sec() *Section {
+	if k.section != nil {
+		return k.section
+	}
+	if s := k.file.section(k.sectionName); s != nil {
+		return s
+	}
+	return &Section{name: k.sectionName, options: make(map[string]string)}
+}
+
+// Name returns the name of the option.
+func (k *Key) Name() string {
+	return k.name
+}
+
+// Section returns the section the option belongs to. For a key returned by
+// IniFile.Key, it is an empty section that is not part of the configuration if the
+// section does not exist.
+func (k *Key) Section() *Section {
+	return k.sec()
+}
+
+// Exists returns true if the option exists.
+func (k *Key) Exists() bool {
+	return k.sec().Exists(k.name)
+}
+
+// Value returns the value of the option, see Section.ValueOf.
+func (k *Key) Value() string {
+	return k.sec().ValueOf(k.name)
+}
+
+// String returns the value of the option.
+func (k *Key) String() string {
+	return k.Value()
+}
+
+// SetValue sets the value of the option, adding it if it does not exist, and returns the old value.
+func (k *Key) SetValue(value string) string {
+	if k.section == nil && !k.file.Frozen() {
+		return k.file.sectionOrAdd(k.sectionName).Add(k.name, value)
+	}
+	return k.sec().Add(k.name, value)
+}
+
+// Comment returns the comment lines preceding the option.
+func (k *Key) Comment() string {
+	return k.sec().CommentFor(k.name)
+}
+
+// In returns the value of the option if it is one of candidates, otherwise fallback.
+func (k *Key) In(fallback string, candidates []string) string {
+	value := k.Value()
+	for _, candidate := range candidates {
+		if value == candidate {
+			return value
+		}
+	}
+	return fallback
+}
+
+// Validate returns the value of the option transformed by fn, which can be used
+// to check or normalize it.
+func (k *Key) Validate(fn func(string) string) string {
+	return fn(k.Value())
+}
+
+// Int returns the value of the option as an int.
+func (k *Key) Int() (int, error) {
+	return k.sec().Int(k.name)
+}
+
+// Int64 returns the value of the option as an int64.
+func (k *Key) Int64() (int64, error) {
+	return k.sec().Int64(k.name)
+}
+
+// Uint64 returns the value of the option as an uint64.
+func (k *Key) Uint64() (uint64, error) {
+	return k.sec().Uint64(k.name)
+}
+
+// Float64 returns the value of the option as a float64.
+func (k *Key) Float64() (float64, error) {
+	return k.sec().Float64(k.name)
+}
+
+// Bool returns the value of the option as a bool, see Section.Bool.
+func (k *Key) Bool() (bool, error) {
+	return k.sec().Bool(k.name)
+}
+
+// Duration returns the value of the option as a time.Duration.
+func (k *Key) Duration() (time.Duration, error) {
+	return k.sec().Duration(k.name)
+}
+
+// Strings returns the value of the option split on delim, see Section.Strings.
+func (k *Key) Strings(delim string) ([]string, error) {
+	return k.sec().Strings(k.name, delim)
+}
+
+// MustString returns the value of the option or fallback if the option does not exist.
+func (k *Key) MustString(fallback string) string {
+	return k.sec().MustString(k.name, fallback)
+}
+
+// MustInt returns the value of the option as an int or fallback if it does not exist or is malformed.
+func (k *Key) MustInt(fallback int) int {
+	return k.sec().MustInt(k.name, fallback)
+}
+
+// MustInt64 returns the value of the option as an int64 or fallback if it does not exist or is malformed.
+func (k *Key) MustInt64(fallback int64) int64 {
+	return k.sec().MustInt64(k.name, fallback)
+}
+
+// MustUint64 returns the value of the option as an uint64 or fallback if it does not exist or is malformed.
+func (k *Key) MustUint64(fallback uint64) uint64 {
+	return k.sec().MustUint64(k.name, fallback)
+}
+
+// MustFloat64 returns the value of the option as a float64 or fallback if it does not exist or is malformed.
+func (k *Key) MustFloat64(fallback float64) float64 {
+	return k.sec().MustFloat64(k.name, fallback)
+}
+
+// MustBool returns the value of the option as a bool or fallback if it does not exist or is malformed.
+func (k *Key) MustBool(fallback bool) bool {
+	return k.sec().MustBool(k.name, fallback)
+}
+
+// MustDuration returns the value of the option as a time.Duration or fallback if it does not exist or is malformed.
+func (k *Key) MustDuration(fallback time.Duration) time.Duration {
+	return k.sec().MustDuration(k.name, fallback)
+}
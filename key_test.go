@@ -0,0 +1,24 @@
+package goini
+
+import "testing"
+
+func TestIniFileKey(t *testing.T) {
+	cfg, err := ParseString("[http]\nport = 9090\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := cfg.Key("http", "port").MustInt(8080); port != 9090 {
+		t.Errorf("port is %d, want 9090", port)
+	}
+	if port := cfg.Key("https", "port").MustInt(8443); port != 8443 {
+		t.Errorf("port of a missing section is %d, want the fallback 8443", port)
+	}
+	if cfg.HasSection("https") {
+		t.Error("reading a key added its section")
+	}
+
+	cfg.Key("https", "port").SetValue("443")
+	if port := cfg.Key("https", "port").MustInt(8443); port != 443 {
+		t.Errorf("port is %d after SetValue, want 443", port)
+	}
+}
@@ -0,0 +1,86 @@
+package goini
+
+import (
+	"fmt"
+	"os"
+)
+
+// Layered resolves lookups across several configurations, such as system, user and
+// local configuration files, the last layer having the highest precedence.
+type Layered struct {
+	layers []*IniFile
+}
+
+// LoadLayers parses the files in paths, from the lowest to the highest precedence.
+// Files that do not exist are skipped.
+func LoadLayers(paths ...string) (*Layered, error) {
+	l := &Layered{}
+	for _, path := range paths {
+		c, err := Parse(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		l.layers = append(l.layers, c)
+	}
+	return l, nil
+}
+
+// NewLayered returns a Layered over already parsed configurations, from the lowest
+// to the highest precedence.
+func NewLayered(layers ...*IniFile) *Layered {
+	return &Layered{layers: layers}
+}
+
+// Layers returns the loaded configurations, from the lowest to the highest precedence.
+func (l *Layered) Layers() []*IniFile {
+	return l.layers
+}
+
+// layer returns the configuration with the highest precedence defining the option, or nil.
+func (l *Layered) layer(section, option string) *IniFile {
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		if l.layers[i].HasOption(section, option) {
+			return l.layers[i]
+		}
+	}
+	return nil
+}
+
+// StringValueOk returns the value of the option from the layer with the highest
+// precedence defining it, and whether any layer defines it.
+func (l *Layered) StringValueOk(section, option string) (string, bool) {
+	if c := l.layer(section, option); c != nil {
+		return c.StringValueOk(section, option)
+	}
+	return "", false
+}
+
+// StringValue returns the value of the option from the layer with the highest precedence defining it.
+func (l *Layered) StringValue(section, option string) (string, error) {
+	if value, ok := l.StringValueOk(section, option); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: %s in section %s", ErrOptionNotFound, option, section)
+}
+
+// Origin returns the file path of the layer the value of the option comes from,
+// and whether any layer defines it.
+func (l *Layered) Origin(section, option string) (string, bool) {
+	if c := l.layer(section, option); c != nil {
+		return c.FilePath(), true
+	}
+	return "", false
+}
+
+// Flatten returns a single configuration with the options of all layers merged
+// according to their precedence.
+func (l *Layered) Flatten() *IniFile {
+	flat := NewIniFile("")
+	for _, c := range l.layers {
+		flat.Merge(c, OverwriteExisting)
+	}
+	return flat
+}
@@ -0,0 +1,205 @@
+package goini
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MergeStrategy controls how Merge handles options existing in both configurations.
+type MergeStrategy int
+
+const (
+	// OverwriteExisting replaces the existing values with the merged ones.
+	OverwriteExisting MergeStrategy = iota
+	// KeepExisting keeps the existing values.
+	KeepExisting
+	// ErrorOnConflict fails without modifying the configuration if an option has
+	// different values in both configurations.
+	ErrorOnConflict
+)
+
+// ErrMergeConflict is returned by Merge with the ErrorOnConflict strategy, wrapped
+// with the conflicting section and option.
+var ErrMergeConflict = errors.New("merge conflict")
+
+// Merge merges the sections and options of other into c. Sections and options
+// missing in c are appended in the order of other; existing options are handled
+// according to strategy. Sections are matched by name and subsection name.
+func (c *IniFile) Merge(other *IniFile, strategy MergeStrategy) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	var sources []*Section
+	for _, s := range other.Sections() {
+		sources = append(sources, s.Clone())
+	}
+
+	if strategy == ErrorOnConflict {
+		for _, src := range sources {
+			dst, err := c.Subsection(src.name, src.subName)
+			if err != nil {
+				continue
+			}
+			for _, opt := range src.orderedOptions {
+				if values := dst.ValuesOf(opt); values != nil && !slices.Equal(values, src.valuesOf(opt)) {
+					return fmt.Errorf("%w: %s in section %s", ErrMergeConflict, opt, src.name)
+				}
+			}
+		}
+	}
+
+	for _, src := range sources {
+		dst, err := c.Subsection(src.name, src.subName)
+		if err != nil {
+			dst = c.AddSubsection(src.name, src.subName)
+			dst.mutex.Lock()
+			dst.comment, dst.trailingComment, dst.parent = src.comment, src.trailingComment, src.parent
+			dst.mutex.Unlock()
+		}
+		dst.merge(src, strategy)
+	}
+	if len(sources) > 0 {
+		c.touch()
+	}
+	return nil
+}
+
+// valuesOf returns all the values of option without locking.
+func (s *Section) valuesOf(option string) []string {
+	value, ok := s.options[option]
+	if !ok {
+		return nil
+	}
+	return append([]string{value}, s.shadows[option]...)
+}
+
+// merge copies the options of src, a section not shared with other goroutines, into s.
+func (s *Section) merge(src *Section, strategy MergeStrategy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, name := range src.orderedOptions {
+		opt := s.key(name)
+		if _, ok := s.options[opt]; ok {
+			if strategy == KeepExisting {
+				continue
+			}
+		} else {
+			s.orderedOptions = append(s.orderedOptions, opt)
+		}
+		s.options[opt] = src.options[name]
+		if shadows, ok := src.shadows[name]; ok {
+			if s.shadows == nil {
+				s.shadows = make(map[string][]string)
+			}
+			s.shadows[opt] = slices.Clone(shadows)
+		} else {
+			delete(s.shadows, opt)
+		}
+		if value, ok := src.bare[name]; ok {
+			if s.bare == nil {
+				s.bare = make(map[string]string)
+			}
+			s.bare[opt] = value
+		} else {
+			delete(s.bare, opt)
+		}
+		if comment, ok := src.comments[name]; ok {
+			if s.comments == nil {
+				s.comments = make(map[string]string)
+			}
+			s.comments[opt] = comment
+		}
+		if comment, ok := src.inlineComments[name]; ok {
+			if s.inlineComments == nil {
+				s.inlineComments = make(map[string]string)
+			}
+			s.inlineComments[opt] = comment
+		}
+	}
+}
+
+// Conflict is an option changed differently in both configurations given to Merge3,
+// or changed upstream in a section removed locally.
+type Conflict struct {
+	Section string
+	SubName string
+	Option  string
+	Base    []string // values in the common ancestor, nil if absent
+	Ours    []string // values in the local configuration, nil if absent
+	Theirs  []string // values in the upstream configuration, nil if absent
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %s: base %q, ours %q, theirs %q", header(c.Section, c.SubName), c.Option, c.Base, c.Ours, c.Theirs)
+}
+
+// Merge3 applies the changes made from base to theirs, such as new upstream defaults,
+// to a copy of ours, which holds local edits of base, and returns it. Options edited
+// differently on both sides keep the local values and are reported as conflicts, as
+// are the options added or modified upstream in sections removed locally, which are
+// not added back. Sections removed upstream are removed if they are left without options.
+func Merge3(base, ours, theirs *IniFile) (*IniFile, []Conflict) {
+	result := ours.Clone()
+	var conflicts []Conflict
+	var removed []*Section
+
+	for _, change := range Diff(base, theirs).Changes {
+		s, err := result.Subsection(change.Section, change.SubName)
+		if change.Option == "" {
+			switch {
+			case change.Kind == ChangeAdded && err != nil:
+				src, _ := theirs.Subsection(change.Section, change.SubName)
+				s = result.AddSubsection(change.Section, change.SubName)
+				s.SetComment(src.Comment())
+				s.SetParent(src.Parent())
+			case change.Kind == ChangeRemoved && err == nil:
+				removed = append(removed, s)
+			}
+			continue
+		}
+
+		var values []string
+		if err == nil {
+			values = s.ValuesOf(change.Option)
+		}
+		_, baseErr := base.Subsection(change.Section, change.SubName)
+		switch {
+		case err != nil && baseErr == nil && change.NewValues != nil:
+			conflicts = append(conflicts, Conflict{
+				Section: change.Section, SubName: change.SubName, Option: change.Option,
+				Base: change.OldValues, Theirs: change.NewValues,
+			})
+		case slices.Equal(values, change.OldValues):
+			if s == nil {
+				s = result.AddSubsection(change.Section, change.SubName)
+			}
+			s.setValues(change.Option, change.NewValues)
+		case !slices.Equal(values, change.NewValues):
+			conflicts = append(conflicts, Conflict{
+				Section: change.Section, SubName: change.SubName, Option: change.Option,
+				Base: change.OldValues, Ours: values, Theirs: change.NewValues,
+			})
+		}
+	}
+
+	for _, s := range removed {
+		if len(s.OptionNames()) == 0 {
+			result.removeSection(s)
+		}
+	}
+	return result, conflicts
+}
+
+// setValues replaces the values of option, deleting it if values is nil.
+func (s *Section) setValues(option string, values []string) {
+	if values == nil {
+		s.Delete(option)
+		return
+	}
+	s.Add(option, values[0])
+	for _, value := range values[1:] {
+		s.AddShadow(option, value)
+	}
+}
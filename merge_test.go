@@ -0,0 +1,73 @@
+package goini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// mustParse parses text, failing the test on error.
+func mustParse(t *testing.T, text string) *IniFile {
+	t.Helper()
+	c, err := ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMerge(t *testing.T) {
+	const base = "[s]\nshared = ours\nmine = 1\n"
+	const other = "[s]\nshared = theirs\nyours = 2\n[remote \"origin\"]\nurl = u\n"
+	tests := map[MergeStrategy]string{
+		OverwriteExisting: "[s]\nshared=theirs\nmine=1\nyours=2\n[remote \"origin\"]\nurl=u\n",
+		KeepExisting:      "[s]\nshared=ours\nmine=1\nyours=2\n[remote \"origin\"]\nurl=u\n",
+	}
+	for strategy, want := range tests {
+		c := mustParse(t, base)
+		if err := c.Merge(mustParse(t, other), strategy); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.String(); got != want {
+			t.Errorf("strategy %d: merged configuration is\n%q\nwant\n%q", strategy, got, want)
+		}
+		if !c.IsModified() {
+			t.Errorf("strategy %d: configuration not modified by the merge", strategy)
+		}
+	}
+}
+
+func TestMergeErrorOnConflict(t *testing.T) {
+	c := mustParse(t, "[s]\nshared = ours\n")
+	if err := c.Merge(mustParse(t, "[s]\nshared = ours\nnew = 1\n"), ErrorOnConflict); err != nil {
+		t.Errorf("merge of equal values failed: %v", err)
+	}
+	if err := c.Merge(mustParse(t, "[s]\nshared = theirs\nother = 2\n[t]\n"), ErrorOnConflict); !errors.Is(err, ErrMergeConflict) {
+		t.Errorf("error is %v, want ErrMergeConflict", err)
+	}
+	if got, want := c.String(), "[s]\nshared=ours\nnew=1\n"; got != want {
+		t.Errorf("configuration is %q after a conflict, want %q", got, want)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := mustParse(t, "[app]\nport = 80\nhost = a\nlevel = info\n[old]\nk = 1\n[local]\nk = 1\n")
+	ours := mustParse(t, "[app]\nport = 8080\nhost = a\nlevel = debug\n[old]\nk = 1\n")
+	theirs := mustParse(t, "[app]\nport = 80\nhost = b\nlevel = warn\ntimeout = 5\n[local]\nk = 2\n[new]\nk = 3\n")
+
+	result, conflicts := Merge3(base, ours, theirs)
+	want := "[app]\nport=8080\nhost=b\nlevel=debug\ntimeout=5\n[new]\nk=3\n"
+	if got := result.String(); got != want {
+		t.Errorf("merged configuration is\n%q\nwant\n%q", got, want)
+	}
+	wantConflicts := []Conflict{
+		{Section: "app", Option: "level", Base: []string{"info"}, Ours: []string{"debug"}, Theirs: []string{"warn"}},
+		{Section: "local", Option: "k", Base: []string{"1"}, Theirs: []string{"2"}},
+	}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("conflicts are\n%v\nwant\n%v", conflicts, wantConflicts)
+	}
+	if got := ours.section("app").ValueOf("host"); got != "a" {
+		t.Errorf("ours modified by the merge: host is %q", got)
+	}
+}
@@ -0,0 +1,983 @@
+package goini
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ParseOptions controls how configuration files are parsed.
+// The zero value parses leniently, keeping malformed lines as they are.
+type ParseOptions struct {
+	// Strict makes the parser fail with a *ParseError on malformed lines,
+	// duplicate sections, duplicate options and lines without a delimiter.
+	Strict bool
+
+	// AllowInlineComments splits "key = value ; comment" and "key = value # comment"
+	// into the value and an inline comment attached to the option. The comment
+	// character must be preceded by whitespace to be recognized. A comment following
+	// the closing bracket of a section header is kept with the section. It sets
+	// SaveOptions.QuoteInlineComments.
+	AllowInlineComments bool
+
+	// AllowLineContinuation joins an option line ending with a backslash with the
+	// following line, whose leading whitespace is removed.
+	AllowLineContinuation bool
+
+	// AllowIndentedContinuation appends indented lines following an option to
+	// its value, separated by newlines, like Python's configparser. It sets
+	// SaveOptions.IndentedContinuation.
+	AllowIndentedContinuation bool
+
+	// ExpandEnv expands ${NAME} and %NAME% references to environment variables
+	// when values are read, see IniFile.SetExpandEnv. Environment variables take
+	// precedence over the option references of Section.ValueOfExpanded.
+	ExpandEnv bool
+
+	// AllowIncludes processes "include = path" and "!include path" lines by parsing
+	// the named file in place. Relative paths are resolved against the directory
+	// of the including file. Paths containing glob patterns include every matching
+	// file in lexical order. "includedir path" and "!includedir path" include the
+	// *.ini, *.cnf and *.conf files of a directory in lexical order.
+	AllowIncludes bool
+
+	// MaxIncludeDepth limits the nesting of included files, 10 if zero: a file
+	// included by the parsed one is at depth 1.
+	MaxIncludeDepth int
+
+	// MaxBytes limits the number of bytes read, included files counted,
+	// unlimited if zero.
+	MaxBytes int64
+
+	// MaxSections limits the number of sections, unlimited if zero.
+	MaxSections int
+
+	// MaxKeysPerSection limits the number of values of a section, repeated
+	// options counted, unlimited if zero.
+	MaxKeysPerSection int
+
+	// MaxLineBytes limits the length of a physical line, 64 KiB if zero. Longer
+	// lines fail the parse with an error wrapping ErrLineTooLong.
+	MaxLineBytes int
+
+	// DuplicateKeyPolicy controls how an option appearing several times in a section is handled.
+	DuplicateKeyPolicy DuplicateKeyPolicy
+
+	// DuplicateSectionPolicy controls how a section declared several times is handled.
+	DuplicateSectionPolicy DuplicateSectionPolicy
+
+	// DecodeUTF16 decodes files starting with a UTF-16 byte order mark. A UTF-8 byte
+	// order mark is always skipped. The encoding is kept by the configuration, see
+	// IniFile.Encoding and SaveOptions.KeepEncoding.
+	DecodeUTF16 bool
+
+	// Charset is the character set of files without byte order mark, transcoded
+	// to UTF-8 while parsing. UTF-8 if empty. The charsets of golang.org/x/text,
+	// such as windows-1252, latin-1, gbk or shift_jis, are supported by their IANA
+	// name or WHATWG label, see RegisterCharset for others.
+	Charset string
+
+	// PreserveFormatting keeps the text of every parsed line so that writing the
+	// configuration reproduces the file byte for byte, except for the lines of the
+	// options whose values changed, see Section.format. Ignored by the properties dialect.
+	PreserveFormatting bool
+
+	// BareKeysAsBool gives the options declared without delimiter, such as "verbose",
+	// the value "true" instead of an empty value, see Section.IsBare.
+	BareKeysAsBool bool
+
+	// Whitespace controls whether the whitespace around option names and values is trimmed.
+	Whitespace WhitespacePolicy
+
+	// RejectIndentedKeys makes option lines starting with spaces or tabs fail the
+	// parse with a *ParseError, unless they continue a value.
+	RejectIndentedKeys bool
+
+	// CommentPrefixes are the prefixes of comment lines, and of inline comments if
+	// AllowInlineComments is set, "#" and ";" if nil. An empty non-nil slice disables
+	// comments. The first prefix is also used for comments added before saving,
+	// see SaveOptions.CommentPrefix.
+	CommentPrefixes []string
+
+	// Delimiters are the characters accepted between option names and values, the
+	// first one found on a line separating them, so that values may contain them.
+	// A space accepts any run of spaces and tabs, as in "key value", unless another
+	// delimiter follows it. '=' and ':' if empty. Otherwise the first delimiter is
+	// also written when saving, see SaveOptions.Delimiter.
+	Delimiters string
+
+	// EscapeValueDelims unescapes "\=" and "\:" in unquoted values, see
+	// SaveOptions.EscapeValueDelims, which it sets.
+	EscapeValueDelims bool
+
+	// EscapeValues replaces the escape sequences of unquoted values, see Unescape
+	// and SaveOptions.EscapeValues, which it sets.
+	EscapeValues bool
+
+	// GlobalSection is the name of the section holding the options preceding the
+	// first section header, "global" if empty. The section is written without header
+	// and is not kept if it is empty once parsed. A section declared with a header of
+	// the same name is a separate section.
+	GlobalSection string
+
+	// DisallowGlobalKeys makes options preceding the first section header fail the
+	// parse with a *ParseError.
+	DisallowGlobalKeys bool
+	// AllowInheritance parses "[child : parent]" headers as a section named child
+	// inheriting the options it lacks from the section named parent, see
+	// Section.Parent. Otherwise the colon is part of the section name, as in "[host:8080]".
+	AllowInheritance bool
+
+	// IgnoreCase makes section and option names match regardless of case, see
+	// IniFile.SetIgnoreCase. Repeated sections and options differing only by case
+	// are handled as duplicates.
+	IgnoreCase bool
+
+	// Dialect selects the syntax of the parsed file. It is kept by the configuration
+	// and used again when writing it, see IniFile.SetDialect.
+	Dialect Dialect
+}
+
+// DuplicateKeyPolicy controls how an option appearing several times in a section is handled.
+type DuplicateKeyPolicy int
+
+const (
+	// LastWins keeps the last value of the option.
+	LastWins DuplicateKeyPolicy = iota
+	// FirstWins keeps the first value of the option.
+	FirstWins
+	// Append keeps every value, the repeated ones as shadow values, see Section.ValuesOf.
+	Append
+	// ErrorOnDuplicate fails the parse with a *ParseError.
+	ErrorOnDuplicate
+)
+
+// DuplicateSectionPolicy controls how a section declared several times is handled.
+type DuplicateSectionPolicy int
+
+const (
+	// KeepSeparate keeps each declaration as a separate Section instance. Section
+	// returns the first one, Sections all of them in order.
+	KeepSeparate DuplicateSectionPolicy = iota
+	// MergeSections merges the options of every declaration into the first Section
+	// instance, applying the DuplicateKeyPolicy to repeated options.
+	MergeSections
+	// ErrorOnDuplicateSection fails the parse with a *ParseError.
+	ErrorOnDuplicateSection
+)
+
+// WhitespacePolicy controls whether the whitespace around option names and values is trimmed.
+type WhitespacePolicy int
+
+const (
+	// TrimWhitespace trims the spaces and tabs around option names and values.
+	TrimWhitespace WhitespacePolicy = iota
+	// PreserveValueWhitespace keeps the whitespace between the delimiter and the end of
+	// the line as part of the value, option names being trimmed.
+	PreserveValueWhitespace
+	// PreserveWhitespace keeps the whitespace around option names and values.
+	PreserveWhitespace
+)
+
+// bareValue returns the value of the options declared without delimiter.
+func (o ParseOptions) bareValue() string {
+	if o.BareKeysAsBool {
+		return "true"
+	}
+	return ""
+}
+
+// defaultCommentPrefixes are used when ParseOptions.CommentPrefixes is nil.
+var defaultCommentPrefixes = []string{"#", ";"}
+
+// commentPrefixes returns the prefixes of comment lines.
+func (o ParseOptions) commentPrefixes() []string {
+	if o.CommentPrefixes == nil {
+		return defaultCommentPrefixes
+	}
+	return o.CommentPrefixes
+}
+
+// defaultMaxIncludeDepth is used when ParseOptions.MaxIncludeDepth is zero.
+const defaultMaxIncludeDepth = 10
+
+// ErrLineTooLong is returned when a line exceeds ParseOptions.MaxLineBytes.
+var ErrLineTooLong = errors.New("line too long")
+
+// ErrLimitExceeded is wrapped by the errors returned when a parsed file exceeds one
+// of the limits of ParseOptions: MaxBytes, MaxSections, MaxKeysPerSection or MaxIncludeDepth.
+var ErrLimitExceeded = errors.New("parse limit exceeded")
+
+// ParseError describes a malformed line encountered while parsing in strict mode.
+type ParseError struct {
+	File string // path of the parsed file, empty when parsing from a reader
+	Line int    // 1-based line number
+	Text string // offending line
+	Msg  string // reason of the failure
+	Err  error  // underlying error, such as ErrLimitExceeded, if any
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: %s: %q", e.File, e.Line, e.Msg, e.Text)
+	}
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Msg, e.Text)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses a specified configuration file using the options o.
+func (o ParseOptions) Parse(filePath string) (*IniFile, error) {
+	filePath = path.Clean(filePath)
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	c, err := o.parse(io.TeeReader(file, hash), filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil {
+		c.loaded = fileState{modTime: info.ModTime(), size: info.Size()}
+		hash.Sum(c.loadedSum[:0])
+	}
+	return c, nil
+}
+
+// ParseFS parses the configuration file name of fsys, such as an embed.FS, using
+// the options o. Included files are read from fsys too, names being resolved as
+// slash-separated paths of fsys. The returned configuration has no file path.
+func (o ParseOptions) ParseFS(fsys fs.FS, name string) (*IniFile, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return o.parse(file, name, fsys)
+}
+
+// ParseReader parses the configuration read from r using the options o.
+func (o ParseOptions) ParseReader(r io.Reader) (*IniFile, error) {
+	return o.parse(r, "", nil)
+}
+
+// ParseBytes parses the configuration contained in data using the options o.
+func (o ParseOptions) ParseBytes(data []byte) (*IniFile, error) {
+	return o.parse(bytes.NewReader(data), "", nil)
+}
+
+// ParseString parses the configuration contained in str using the options o.
+func (o ParseOptions) ParseString(str string) (*IniFile, error) {
+	return o.parse(strings.NewReader(str), "", nil)
+}
+
+// parser holds the state of a single parse run.
+type parser struct {
+	opts      ParseOptions
+	file      *IniFile
+	filePath  string
+	lineNo    int
+	active    *Section
+	seen      map[string]bool // explicitly declared sections
+	comments  []string        // comment lines waiting for the next option or section
+	last      string          // option continued by indented lines
+	includes  []string        // absolute paths of the files being parsed
+	preserve  bool            // record the raw lines, see ParseOptions.PreserveFormatting
+	rawText   string          // physical lines of the line being parsed
+	recorded  bool            // the line being parsed was recorded
+	including int             // depth of the included file being parsed
+	read      int64           // bytes read, see ParseOptions.MaxBytes
+	fsys      fs.FS           // file system of the files parsed, nil for the OS one
+	open      func(name string) (io.ReadCloser, error)
+	glob      func(pattern string) ([]string, error)
+}
+
+// parse parses the configuration read from r, the file filePath of fsys if not nil,
+// otherwise of the OS file system.
+func (o ParseOptions) parse(r io.Reader, filePath string, fsys fs.FS) (*IniFile, error) {
+	// New File
+	p := &parser{opts: o, file: NewIniFile(filePath), filePath: filePath, seen: make(map[string]bool), fsys: fsys}
+	if fsys != nil {
+		p.file.filePath = ""
+		p.open = func(name string) (io.ReadCloser, error) { return fsys.Open(name) }
+		p.glob = func(pattern string) ([]string, error) { return fs.Glob(fsys, pattern) }
+	} else {
+		p.open = func(name string) (io.ReadCloser, error) { return os.Open(name) }
+		p.glob = filepath.Glob
+	}
+	p.file.saveOptions.QuoteInlineComments = o.AllowInlineComments
+	p.file.saveOptions.IndentedContinuation = o.AllowIndentedContinuation
+	p.file.expandEnv = o.ExpandEnv
+	p.file.sectionPolicy = o.DuplicateSectionPolicy
+	p.file.dialect = o.Dialect
+	p.file.ignoreCase.Store(o.IgnoreCase)
+	if o.Delimiters != "" {
+		p.file.saveOptions.Delimiter = o.Delimiters[:1]
+	}
+	if len(o.CommentPrefixes) > 0 {
+		p.file.saveOptions.CommentPrefix = o.CommentPrefixes[0]
+	}
+	p.file.saveOptions.EscapeValueDelims = o.EscapeValueDelims
+	p.file.saveOptions.EscapeValues = o.EscapeValues
+	p.file.parseOptions = o
+	p.file.globalName = o.GlobalSection
+	p.active = p.file.AddSection(p.file.GlobalSectionName())
+	p.active.position = Position{File: filePath, Line: 1}
+	if o.PreserveFormatting && o.Dialect != DialectProperties {
+		p.preserve = true
+		p.active.raw = p.newRawSection(p.active)
+	}
+	if filePath != "" {
+		if abs, err := p.abs(filePath); err == nil {
+			p.includes = append(p.includes, abs)
+		}
+	}
+
+	r, encoding, err := p.decode(p.limit(r))
+	if err != nil {
+		return nil, err
+	}
+	p.file.encoding = encoding
+
+	detector := &newlineDetector{r: r}
+	if err := p.parseReader(detector); err != nil {
+		return nil, err
+	}
+	p.active.trailingComment = p.takeComment()
+	if detector.crlf {
+		p.file.newline = "\r\n"
+	}
+	p.dropEmptyGlobal()
+	p.file.clearModified()
+
+	return p.file, nil
+}
+
+// dropEmptyGlobal removes the global section if nothing precedes the first section header.
+func (p *parser) dropEmptyGlobal() {
+	global := p.file.globalSection()
+	if global == nil || len(global.orderedOptions) > 0 || global.comment != "" || global.trailingComment != "" ||
+		(global.raw != nil && len(global.raw.lines) > 0) {
+		return
+	}
+	p.file.removeSection(global)
+	if global.raw != nil {
+		p.file.raws = slices.DeleteFunc(p.file.raws, func(r *rawSection) bool { return r == global.raw })
+	}
+}
+
+// newlineDetector records whether the first line read through it ends with "\r\n".
+type newlineDetector struct {
+	r      io.Reader
+	done   bool
+	prevCR bool
+	crlf   bool
+}
+
+func (d *newlineDetector) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	for i := 0; i < n && !d.done; i++ {
+		if b[i] == '\n' {
+			d.crlf, d.done = d.prevCR, true
+		}
+		d.prevCR = b[i] == '\r'
+	}
+	return n, err
+}
+
+// parseReader parses the lines read from r into the configuration.
+func (p *parser) parseReader(r io.Reader) error {
+	var (
+		lineNo    int
+		continued bool   // the previous line ended with a backslash
+		pending   string // the joined lines so far
+		rawText   string // the physical lines joined so far
+	)
+	maxLine := p.opts.MaxLineBytes
+	if maxLine <= 0 {
+		maxLine = bufio.MaxScanTokenSize
+	}
+	scanner := bufio.NewScanner(bufio.NewReader(r))
+	scanner.Buffer(make([]byte, 0, min(maxLine, 4096)), maxLine+2) // room for "\r\n"
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if len(line) > maxLine {
+			return p.lineTooLong(lineNo, maxLine)
+		}
+		if continued {
+			rawText += "\n" + line
+			if p.opts.Dialect == DialectSystemd && p.isComment(strings.TrimLeft(line, " \t")) {
+				continue // comment lines between continued lines are ignored
+			}
+			line = pending + strings.TrimLeft(line, " \t")
+		} else {
+			p.lineNo, rawText = lineNo, line
+		}
+		if p.continues(line) {
+			pending, continued = line[:len(line)-1], true
+			if p.opts.Dialect == DialectSystemd {
+				pending += " "
+			}
+			continue
+		}
+		continued = false
+		if err := p.parseRawLine(line, rawText); err != nil {
+			return err
+		}
+	}
+	if continued {
+		if err := p.parseRawLine(pending, rawText); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return p.lineTooLong(lineNo+1, maxLine)
+		}
+		return err
+	}
+	return nil
+}
+
+// lineTooLong returns the error for line lineNo exceeding maxLine bytes.
+func (p *parser) lineTooLong(lineNo, maxLine int) error {
+	if p.filePath != "" {
+		return fmt.Errorf("%s:%d: %w: longer than %d bytes", p.filePath, lineNo, ErrLineTooLong, maxLine)
+	}
+	return fmt.Errorf("line %d: %w: longer than %d bytes", lineNo, ErrLineTooLong, maxLine)
+}
+
+// continues returns true if line is continued on the following line.
+func (p *parser) continues(line string) bool {
+	switch p.opts.Dialect {
+	case DialectProperties:
+		return continuesProperty(line)
+	case DialectSystemd:
+		return strings.HasSuffix(line, "\\") && !p.isComment(strings.TrimLeft(line, " \t"))
+	}
+	return p.opts.AllowLineContinuation && strings.HasSuffix(line, "\\") && !p.isComment(line)
+}
+
+// includeExts lists the extensions of the files included by an includedir directive.
+var includeExts = []string{".ini", ".cnf", ".conf"}
+
+// include parses the files named by the include directive line in place of it.
+func (p *parser) include(line, name string, dir bool) error {
+	name = p.resolve(name)
+	if !dir && !strings.ContainsAny(name, "*?[") {
+		return p.includeFile(line, name)
+	}
+
+	var names []string
+	if dir {
+		for _, ext := range includeExts {
+			matches, err := p.glob(p.join(name, "*"+ext))
+			if err != nil {
+				return p.errorf(line, "unable to include %s: %v", name, err)
+			}
+			names = append(names, matches...)
+		}
+		sort.Strings(names)
+	} else {
+		var err error
+		if names, err = p.glob(name); err != nil {
+			return p.errorf(line, "unable to include %s: %v", name, err)
+		}
+	}
+	for _, name := range names {
+		if err := p.includeFile(line, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode returns a reader of the content of r as UTF-8 and the encoding of r: its
+// byte order mark is skipped, UTF-16 decoded if ParseOptions.DecodeUTF16 is set, and
+// content without byte order mark is transcoded from ParseOptions.Charset.
+func (p *parser) decode(r io.Reader) (io.Reader, Encoding, error) {
+	r, encoding, err := decodeBOM(r, p.opts.DecodeUTF16)
+	if err != nil || encoding != EncodingUTF8 {
+		return r, encoding, err
+	}
+	r, err = decodeCharset(r, p.opts.Charset)
+	return r, encoding, err
+}
+
+// resolve returns the path of the file name included by the file being parsed.
+func (p *parser) resolve(name string) string {
+	switch {
+	case p.fsys != nil && path.IsAbs(name):
+		return path.Clean(name)[1:] // relative to the root of fsys
+	case p.fsys != nil:
+		return path.Join(path.Dir(p.filePath), name)
+	case filepath.IsAbs(name):
+		return name
+	}
+	return filepath.Join(filepath.Dir(p.filePath), name)
+}
+
+// join joins path elements with the separator of the file system parsed.
+func (p *parser) join(elem ...string) string {
+	if p.fsys != nil {
+		return path.Join(elem...)
+	}
+	return filepath.Join(elem...)
+}
+
+// abs returns the absolute path of the file name, identifying it among the files
+// being parsed.
+func (p *parser) abs(name string) (string, error) {
+	if p.fsys != nil {
+		return path.Clean(name), nil
+	}
+	return filepath.Abs(name)
+}
+
+// includeFile parses the file name in place of the include directive line.
+func (p *parser) includeFile(line, name string) error {
+	maxDepth := p.opts.MaxIncludeDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxIncludeDepth
+	}
+	if p.including >= maxDepth {
+		return p.limitErrorf(line, "maximum include depth of %d exceeded", maxDepth)
+	}
+	abs, err := p.abs(name)
+	if err != nil {
+		return p.errorf(line, "unable to include %s: %v", name, err)
+	}
+	for _, included := range p.includes {
+		if included == abs {
+			return p.errorf(line, "include cycle on %s", name)
+		}
+	}
+	f, err := p.open(name)
+	if err != nil {
+		return p.errorf(line, "unable to include %s: %v", name, err)
+	}
+	defer f.Close()
+
+	r, _, err := p.decode(p.limit(f))
+	if err != nil {
+		return p.errorf(line, "unable to include %s: %v", name, err)
+	}
+
+	filePath, lineNo, active := p.filePath, p.lineNo, p.active
+	p.filePath, p.includes = name, append(p.includes, abs)
+	p.including++
+	err = p.parseReader(r)
+	p.including--
+	p.filePath, p.lineNo, p.active = filePath, lineNo, active
+	p.includes = p.includes[:len(p.includes)-1]
+	p.last = ""
+	return err
+}
+
+// includePath returns the path named by an include directive line, if line is one,
+// and whether the directive includes a directory.
+func includePath(line, delims string) (name string, dir bool, ok bool) {
+	// "includedir = path" before "includedir path"
+	if delimiterIndex(line, delims) != -1 {
+		switch opt, value := parseOption(line, delims); opt {
+		case "include":
+			return value, false, true
+		case "includedir":
+			return value, true, true
+		}
+	}
+	for _, directive := range []string{"!include ", "!includedir ", "includedir "} {
+		if strings.HasPrefix(line, directive) {
+			return strings.TrimSpace(line[len(directive):]), strings.HasSuffix(directive, "dir "), true
+		}
+	}
+	return "", false, false
+}
+
+// errorf returns a *ParseError for the current line.
+func (p *parser) errorf(line string, format string, args ...interface{}) error {
+	return &ParseError{File: p.filePath, Line: p.lineNo, Text: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// limitErrorf returns a *ParseError wrapping ErrLimitExceeded for the current line.
+func (p *parser) limitErrorf(line string, format string, args ...interface{}) error {
+	err := p.errorf(line, format, args...)
+	err.(*ParseError).Err = ErrLimitExceeded
+	return err
+}
+
+// limit returns r, failing with ErrLimitExceeded once more than ParseOptions.MaxBytes
+// bytes were read through the readers returned for the parse.
+func (p *parser) limit(r io.Reader) io.Reader {
+	if p.opts.MaxBytes <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, p: p}
+}
+
+// limitedReader counts the bytes read from r into the parser.
+type limitedReader struct {
+	r io.Reader
+	p *parser
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.p.read += int64(n)
+	if l.p.read > l.p.opts.MaxBytes {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, l.p.opts.MaxBytes)
+	}
+	return n, err
+}
+
+// checkKeys fails if the active section has more values than ParseOptions.MaxKeysPerSection.
+func (p *parser) checkKeys(line string) error {
+	maxKeys := p.opts.MaxKeysPerSection
+	if maxKeys <= 0 {
+		return nil
+	}
+	p.active.mutex.RLock()
+	n := len(p.active.options)
+	for _, values := range p.active.shadows {
+		n += len(values)
+	}
+	p.active.mutex.RUnlock()
+	if n > maxKeys {
+		return p.limitErrorf(line, "more than %d options in section %s", maxKeys, p.active.Name())
+	}
+	return nil
+}
+
+// parseRawLine parses line, made of the physical lines rawText, and records it
+// when formatting is preserved.
+func (p *parser) parseRawLine(line, rawText string) error {
+	p.rawText, p.recorded = rawText, false
+	if err := p.parseLine(line); err != nil {
+		return err
+	}
+	if err := p.checkKeys(line); err != nil {
+		return err
+	}
+	if !p.recorded {
+		p.recordLine()
+	}
+	return nil
+}
+
+func (p *parser) parseLine(line string) error {
+	if p.opts.Dialect == DialectProperties {
+		return p.parsePropertiesLine(line)
+	}
+	if p.opts.Dialect == DialectSystemd {
+		line = strings.TrimSpace(line)
+	}
+	if strings.TrimSpace(line) == "" {
+		p.last = ""
+		return nil
+	}
+	if p.opts.AllowIndentedContinuation && p.last != "" && (line[0] == ' ' || line[0] == '\t') {
+		if text := strings.TrimSpace(line); !p.isComment(text) {
+			text = p.active.ValueOfRaw(p.last) + "\n" + text
+			p.active.Add(p.last, text)
+			p.recordContinuation(p.last)
+			return nil
+		}
+	}
+	if p.isComment(strings.TrimLeft(line, " \t")) {
+		// save comments, they are attached to the following option or section
+		p.comments = append(p.comments, strings.TrimRight(line, "\r")) // a stray carriage return could not be written back
+		return nil
+	}
+
+	if isSection(strings.TrimLeft(line, " \t")) {
+		text, comment := strings.TrimSpace(line), ""
+		if p.opts.AllowInlineComments {
+			text, comment = splitHeaderComment(text, p.opts.commentPrefixes())
+		}
+		if p.opts.Strict && !strings.HasSuffix(text, "]") {
+			return p.errorf(line, "malformed section header")
+		}
+		name, subName, parent := parseSectionHeader(text, p.opts.AllowInheritance)
+		if p.opts.Strict && name == "" {
+			return p.errorf(line, "empty section name")
+		}
+		key := name + "\x00" + subName
+		if p.opts.IgnoreCase {
+			key = strings.ToLower(name) + "\x00" + subName
+		}
+		if p.seen[key] && (p.opts.Strict || p.opts.DuplicateSectionPolicy == ErrorOnDuplicateSection) {
+			return p.errorf(line, "duplicate section %s", strings.Trim(line, " []"))
+		}
+		p.seen[key] = true
+		previous := p.active
+		p.active = p.file.AddSubsection(name, subName)
+		p.active.global = false                                                                          // merged into the global section
+		if maxSections := p.opts.MaxSections; maxSections > 0 && p.file.sectionCount()-1 > maxSections { // global not counted
+			return p.limitErrorf(line, "more than %d sections", maxSections)
+		}
+		if !p.active.position.IsValid() {
+			p.active.position = p.position()
+		}
+		if comment != "" {
+			p.active.headerComment = comment
+		}
+		if comment := p.takeComment(); comment != "" {
+			if p.active.comment != "" {
+				comment = p.active.comment + "\n" + comment
+			}
+			p.active.comment = comment
+		}
+		if parent != "" {
+			p.active.parent = parent
+		}
+		p.recordHeader(previous)
+		p.last = ""
+		return nil
+	}
+
+	if p.opts.DisallowGlobalKeys && p.active.global {
+		return p.errorf(line, "option outside of any section")
+	}
+
+	if p.opts.Dialect == DialectSystemd {
+		return p.parseSystemdOption(line)
+	}
+
+	if p.opts.RejectIndentedKeys && (line[0] == ' ' || line[0] == '\t') {
+		return p.errorf(line, "indented option")
+	}
+
+	if p.opts.AllowIncludes {
+		if name, dir, ok := includePath(line, p.opts.Delimiters); ok {
+			p.recordLine()
+			return p.include(line, name, dir)
+		}
+	}
+
+	if p.opts.Strict {
+		opt, _ := p.parseOption(line)
+		if opt == "" {
+			return p.errorf(line, "missing option name")
+		}
+		if delimiterIndex(line, p.opts.Delimiters) == -1 {
+			return p.errorf(line, "missing delimiter")
+		}
+		if p.active.Exists(opt) && !isArrayKey(opt) {
+			return p.errorf(line, "duplicate option %s", opt)
+		}
+	}
+	var comment string
+	if p.opts.AllowInlineComments {
+		line, comment = splitInlineComment(line, p.opts.Delimiters, p.opts.commentPrefixes())
+	}
+	opt, value := p.parseOption(line)
+	bare := delimiterIndex(line, p.opts.Delimiters) == -1
+	if bare {
+		value = p.opts.bareValue()
+	}
+	if isArrayKey(opt) && p.active.Exists(opt) {
+		// "key[] = value" lines always append, whatever the duplicate key policy
+		p.active.AddShadow(opt, value)
+		p.recordOption(opt)
+		p.last = ""
+		return nil
+	}
+	existed := p.active.Exists(opt)
+	if existed {
+		switch p.opts.DuplicateKeyPolicy {
+		case FirstWins:
+			p.last = ""
+			return nil
+		case Append:
+			p.active.AddShadow(opt, value)
+			p.recordOption(opt)
+			p.last = ""
+			return nil
+		case ErrorOnDuplicate:
+			return p.errorf(line, "duplicate option %s", opt)
+		}
+	}
+	if bare {
+		p.active.addBare(opt, value)
+	} else {
+		p.active.Add(opt, value)
+		p.last = opt
+	}
+	if p.active.Exists(opt) {
+		if existed {
+			p.forgetOption(opt) // overridden by this line
+		}
+		p.recordOption(opt)
+		if comment != "" {
+			p.active.SetInlineCommentFor(opt, comment)
+		}
+		if len(p.comments) > 0 {
+			p.active.SetCommentFor(opt, p.takeComment())
+		}
+	}
+	return nil
+}
+
+// splitHeaderComment splits a section header line into the header, up to its closing
+// bracket, and the inline comment starting with one of prefixes that follows it.
+// Brackets inside quoted names, as in `["a]b"]`, do not close the header.
+func splitHeaderComment(line string, prefixes []string) (header, comment string) {
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			end := quoteEnd(line[i:])
+			if end == -1 {
+				return line, ""
+			}
+			i += end
+		case ']':
+			if rest := strings.TrimLeft(line[i+1:], " \t"); isComment(rest, prefixes) {
+				return line[:i+1], rest
+			}
+			return line, ""
+		}
+	}
+	return line, ""
+}
+
+// parseOption splits an option line into the option name and its unquoted value,
+// unescaping unquoted values as required by ParseOptions.EscapeValueDelims and
+// EscapeValues, and keeping the whitespace around them as required by ParseOptions.Whitespace.
+// Quoted values are unquoted whatever the policy.
+func (p *parser) parseOption(line string) (opt, value string) {
+	opt, value = p.splitOption(line)
+	if (p.opts.EscapeValueDelims || p.opts.EscapeValues) && strings.Contains(value, "\\") {
+		if i := delimiterIndex(line, p.opts.Delimiters); i != -1 && !isQuoted(strings.Trim(line[i+1:], " \t")) {
+			if p.opts.EscapeValueDelims {
+				value = valueDelimUnescaper.Replace(value)
+			}
+			if p.opts.EscapeValues {
+				value = Unescape(value)
+			}
+		}
+	}
+	return opt, value
+}
+
+// splitOption splits an option line into the option name and its unquoted value,
+// see parseOption.
+func (p *parser) splitOption(line string) (opt, value string) {
+	if p.opts.Whitespace == TrimWhitespace {
+		return parseOption(line, p.opts.Delimiters)
+	}
+	i := delimiterIndex(line, p.opts.Delimiters)
+	if i == -1 {
+		if p.opts.Whitespace == PreserveValueWhitespace {
+			return strings.Trim(line, " \t"), ""
+		}
+		return line, ""
+	}
+	opt, value = line[:i], line[i+1:]
+	if p.opts.Whitespace == PreserveValueWhitespace {
+		opt = strings.Trim(opt, " \t")
+	}
+	if quoted := strings.Trim(value, " \t"); isQuoted(quoted) {
+		value = unescapeValue(quoted[1 : len(quoted)-1])
+	}
+	return opt, value
+}
+
+// parseSectionHeader returns the section name, the optional subsection name and the optional
+// parent section name of a "[name]", `[name "subsection"]` or, if inherit is true,
+// "[name : parent]" header. The name may be quoted, as in `["odd] name"]`, to contain
+// brackets, quotes, colons and leading or trailing spaces.
+func parseSectionHeader(line string, inherit bool) (name, subName, parent string) {
+	name = strings.TrimPrefix(line, "[")
+	if i := strings.LastIndex(name, "]"); i != -1 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+	if end := quoteEnd(name); len(name) > 0 && name[0] == '"' && end != -1 {
+		rest := strings.TrimSpace(name[end+1:])
+		name = unescapeValue(name[1:end])
+		if unquoted, ok := unquoteName(rest); ok {
+			subName = unquoted
+		} else if after, ok := strings.CutPrefix(rest, ":"); ok && inherit {
+			parent, _ = unquoteName(strings.TrimSpace(after))
+		}
+		return name, subName, parent
+	}
+	if i := strings.Index(name, " \""); i != -1 && !(inherit && strings.Contains(name[:i], ":")) && len(name) > i+2 && strings.HasSuffix(name, "\"") {
+		return name[:i], unescapeValue(name[i+2 : len(name)-1]), ""
+	}
+	if i := strings.Index(name, ":"); i != -1 && inherit {
+		name, parent = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+		parent, _ = unquoteName(parent)
+	}
+	return name, "", parent
+}
+
+// unquoteName returns the name between the double quotes of s and true, or s and
+// false if s is not quoted.
+func unquoteName(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || quoteEnd(s) != len(s)-1 {
+		return s, false
+	}
+	return unescapeValue(s[1 : len(s)-1]), true
+}
+
+// isComment returns true if line is a comment line.
+func (p *parser) isComment(line string) bool {
+	return isComment(line, p.opts.commentPrefixes())
+}
+
+// isComment returns true if line starts with one of the comment prefixes.
+func isComment(line string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeComment returns the pending comment lines and resets them.
+func (p *parser) takeComment() string {
+	comment := strings.Join(p.comments, "\n")
+	p.comments = nil
+	return comment
+}
+
+// splitInlineComment splits line into its content and a trailing comment starting
+// with one of prefixes preceded by whitespace. Comment characters inside a quoted
+// value are ignored.
+func splitInlineComment(line, delims string, prefixes []string) (content, comment string) {
+	start := 1
+	if i := delimiterIndex(line, delims); i != -1 {
+		value := strings.TrimLeft(line[i+1:], " ")
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+			if end := quoteEnd(value); end != -1 {
+				start = len(line) - len(value) + end + 1
+			}
+		}
+	}
+	for i := start; i < len(line); i++ {
+		if (line[i-1] == ' ' || line[i-1] == '\t') && isComment(line[i:], prefixes) {
+			return strings.TrimRight(line[:i], " \t"), line[i:]
+		}
+	}
+	return line, ""
+}
@@ -0,0 +1,391 @@
+package goini
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIndentedContinuation(t *testing.T) {
+	c, err := ParseOptions{AllowIndentedContinuation: true}.ParseString("[s]\nk = first\n  second\n\tthird\nnext = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.section("s").ValueOf("k"), "first\nsecond\nthird"; got != want {
+		t.Errorf("k is %q, want %q", got, want)
+	}
+	if got, want := c.String(), "[s]\nk=first\n\tsecond\n\tthird\nnext=1\n"; got != want {
+		t.Errorf("written configuration is\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestIndentedContinuationKeepsReferences(t *testing.T) {
+	t.Setenv("GOINI_TEST_DIR", "/expanded")
+	c, err := ParseOptions{AllowIndentedContinuation: true, ExpandEnv: true}.ParseString("[s]\nk = ${GOINI_TEST_DIR}\n\tmore\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	if got, want := s.ValueOf("k"), "/expanded\nmore"; got != want {
+		t.Errorf("k is %q, want %q", got, want)
+	}
+	if got, want := s.ValueOfRaw("k"), "${GOINI_TEST_DIR}\nmore"; got != want {
+		t.Errorf("raw value of k is %q, want %q", got, want)
+	}
+	if got, want := c.String(), "[s]\nk=${GOINI_TEST_DIR}\n\tmore\n"; got != want {
+		t.Errorf("written configuration is\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestHeaderInlineComments(t *testing.T) {
+	tests := []struct {
+		text, name, comment string
+	}{
+		{"[s] # comment", "s", "# comment"},
+		{"[s]; comment", "s", "; comment"},
+		{`["a]b"] # comment`, "a]b", "# comment"},
+		{`["a] # b"]`, "a] # b", ""},
+		{`["a\"] ; c"] ; comment`, `a"] ; c`, "; comment"},
+	}
+	for _, test := range tests {
+		c, err := ParseOptions{AllowInlineComments: true}.ParseString(test.text + "\nk = v\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := c.section(test.name)
+		if s == nil {
+			t.Errorf("%s: no section %q in %q", test.text, test.name, c.SectionNames())
+			continue
+		}
+		if s.headerComment != test.comment {
+			t.Errorf("%s: header comment is %q, want %q", test.text, s.headerComment, test.comment)
+		}
+		if got := s.ValueOf("k"); got != "v" {
+			t.Errorf("%s: k is %q, want v", test.text, got)
+		}
+	}
+}
+
+func TestHeaderInlineCommentPrefixes(t *testing.T) {
+	opts := ParseOptions{AllowInlineComments: true, CommentPrefixes: []string{"//"}}
+	c, err := opts.ParseString("[s] // comment\nk = v\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.section("s"); s == nil || s.headerComment != "// comment" {
+		t.Errorf("sections are %q, want s with the comment // comment", c.SectionNames())
+	}
+	c, err = opts.ParseString("[s] # not a comment\nk = v\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.section("s"); s != nil && s.headerComment != "" {
+		t.Errorf("header comment is %q, want none", s.headerComment)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := map[string]int{
+		"[s]\nk = 1\n[broken\n":      3,
+		"[s]\nk = 1\nno delimiter\n": 3,
+		"[s]\nk = 1\nk = 2\n":        3,
+		"[s]\n[]\n":                  2,
+		"[s]\n[t]\n[s]\n":            3,
+	}
+	for text, line := range tests {
+		_, err := ParseOptions{Strict: true}.ParseString(text)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: error is %v, want a *ParseError", text, err)
+			continue
+		}
+		if perr.Line != line {
+			t.Errorf("%q: error at line %d, want %d", text, perr.Line, line)
+		}
+		if _, err := ParseString(text); err != nil {
+			t.Errorf("%q: lenient parse failed: %v", text, err)
+		}
+	}
+}
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	const text = "[s]\nk = 1\nk = 2\nk = 3\n"
+	tests := map[DuplicateKeyPolicy][]string{
+		LastWins:  {"3"},
+		FirstWins: {"1"},
+		Append:    {"1", "2", "3"},
+	}
+	for policy, want := range tests {
+		c, err := ParseOptions{DuplicateKeyPolicy: policy}.ParseString(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.section("s").ValuesOf("k"); !slices.Equal(got, want) {
+			t.Errorf("policy %d: values are %q, want %q", policy, got, want)
+		}
+	}
+	var perr *ParseError
+	if _, err := (ParseOptions{DuplicateKeyPolicy: ErrorOnDuplicate}).ParseString(text); !errors.As(err, &perr) || perr.Line != 3 {
+		t.Errorf("error is %v, want a *ParseError at line 3", err)
+	}
+}
+
+func TestDuplicateSectionPolicy(t *testing.T) {
+	const text = "[s]\na = 1\nk = 1\n[t]\n[s]\nb = 2\nk = 2\n"
+
+	c, err := ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections := c.SectionsByName("s"); len(sections) != 2 || sections[0].ValueOf("k") != "1" || sections[1].ValueOf("k") != "2" {
+		t.Errorf("KeepSeparate: %d sections named s, want both declarations", len(sections))
+	}
+
+	c, err = ParseOptions{DuplicateSectionPolicy: MergeSections}.ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections := c.SectionsByName("s"); len(sections) != 1 {
+		t.Errorf("MergeSections: %d sections named s, want 1", len(sections))
+	}
+	if got, want := c.section("s").OptionNames(), []string{"a", "k", "b"}; !slices.Equal(got, want) {
+		t.Errorf("MergeSections: options are %q, want %q", got, want)
+	}
+	if got := c.section("s").ValueOf("k"); got != "2" {
+		t.Errorf("MergeSections: k is %q, want the last value", got)
+	}
+
+	var perr *ParseError
+	if _, err := (ParseOptions{DuplicateSectionPolicy: ErrorOnDuplicateSection}).ParseString(text); !errors.As(err, &perr) || perr.Line != 5 {
+		t.Errorf("ErrorOnDuplicateSection: error is %v, want a *ParseError at line 5", err)
+	}
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	long := "[s]\nk = " + strings.Repeat("x", 100<<10) + "\n"
+	if _, err := ParseString(long); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("error is %v, want ErrLineTooLong past the default limit", err)
+	}
+	c, err := ParseOptions{MaxLineBytes: 200 << 10}.ParseString(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.section("s").ValueOf("k")); got != 100<<10 {
+		t.Errorf("value has %d bytes, want %d", got, 100<<10)
+	}
+	if _, err := (ParseOptions{MaxLineBytes: 8}).ParseString("[s]\nkey = value\n"); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("error is %v, want ErrLineTooLong", err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := map[string]ParseOptions{
+		"[a]\n[b]\n[c]\n":            {MaxSections: 2},
+		"[s]\na = 1\nb = 2\nb = 3\n": {MaxKeysPerSection: 2, DuplicateKeyPolicy: Append},
+		"[s]\nk = 0123456789\n":      {MaxBytes: 10},
+	}
+	for text, opts := range tests {
+		if _, err := opts.ParseString(text); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%q: error is %v, want ErrLimitExceeded", text, err)
+		}
+		if _, err := ParseString(text); err != nil {
+			t.Errorf("%q: unlimited parse failed: %v", text, err)
+		}
+	}
+	if _, err := (ParseOptions{MaxSections: 2, MaxKeysPerSection: 2, MaxBytes: 64}).ParseString("g = 1\n[a]\nk = 1\n[b]\nk = 2\n"); err != nil {
+		t.Errorf("parse within the limits failed: %v", err)
+	}
+}
+
+func TestDelimiters(t *testing.T) {
+	c, err := ParseOptions{Delimiters: " "}.ParseString("[s]\nkey value with spaces\ntabbed\t\tvalue\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	if got := s.ValueOf("key"); got != "value with spaces" {
+		t.Errorf("key is %q, want the rest of the line", got)
+	}
+	if got := s.ValueOf("tabbed"); got != "value" {
+		t.Errorf("tabbed is %q, want value", got)
+	}
+	if got, want := c.String(), "[s]\nkey value with spaces\ntabbed value\n"; got != want {
+		t.Errorf("written configuration is %q, want %q", got, want)
+	}
+
+	c, err = ParseOptions{Delimiters: ":"}.ParseString("[s]\nurl: http://h/?a=b\nk=v\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = c.section("s")
+	if got := s.ValueOf("url"); got != "http://h/?a=b" {
+		t.Errorf("url is %q, want the text after the first colon", got)
+	}
+	if !s.Exists("k=v") {
+		t.Errorf("options are %q, want k=v read as a bare key", s.OptionNames())
+	}
+}
+
+func TestCommentPrefixes(t *testing.T) {
+	c, err := ParseOptions{CommentPrefixes: []string{"//"}, AllowInlineComments: true}.ParseString(
+		"// about s\n[s]\n// about k\nk = v // inline\n#hash = 1\n;semi = 2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	if got, want := s.OptionNames(), []string{"k", "#hash", ";semi"}; !slices.Equal(got, want) {
+		t.Errorf("options are %q, want %q", got, want)
+	}
+	if got := s.ValueOf("k"); got != "v" {
+		t.Errorf("k is %q, want v", got)
+	}
+	if got := s.CommentFor("k"); got != "// about k" {
+		t.Errorf("comment of k is %q", got)
+	}
+
+	c, err = ParseOptions{CommentPrefixes: []string{}}.ParseString("[s]\n# k = v\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.section("s").ValueOf("# k"); got != "v" {
+		t.Errorf("options are %q, want # k read as an option with comments disabled", c.section("s").OptionNames())
+	}
+}
+
+func TestWhitespacePolicy(t *testing.T) {
+	const text = "[s]\n  key  =  value  \n"
+	tests := map[WhitespacePolicy][2]string{
+		TrimWhitespace:          {"key", "value"},
+		PreserveValueWhitespace: {"key", "  value  "},
+		PreserveWhitespace:      {"  key  ", "  value  "},
+	}
+	for policy, want := range tests {
+		c, err := ParseOptions{Whitespace: policy}.ParseString(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := c.section("s")
+		if got := s.OptionNames(); !slices.Equal(got, want[:1]) {
+			t.Errorf("policy %d: options are %q, want %q", policy, got, want[0])
+			continue
+		}
+		if got := s.ValueOf(want[0]); got != want[1] {
+			t.Errorf("policy %d: value is %q, want %q", policy, got, want[1])
+		}
+		if got := roundTrip(t, c, ParseOptions{Whitespace: policy}).section("s").ValueOf(want[0]); got != want[1] {
+			t.Errorf("policy %d: value read back is %q, want %q", policy, got, want[1])
+		}
+	}
+}
+
+func TestBareKeys(t *testing.T) {
+	const text = "[s]\nverbose\nempty =\nset = 1\n"
+	c, err := ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	for opt, want := range map[string][2]bool{"verbose": {true, false}, "empty": {false, true}, "set": {false, true}} {
+		if got := [2]bool{s.IsBare(opt), s.IsSet(opt)}; got != want {
+			t.Errorf("%s: IsBare, IsSet are %v, want %v", opt, got, want)
+		}
+	}
+	if got := s.ValueOf("verbose"); got != "" {
+		t.Errorf("verbose is %q, want empty", got)
+	}
+	if got := c.String(); got != "[s]\nverbose\nempty=\nset=1\n" {
+		t.Errorf("written configuration is %q, want the bare key kept bare", got)
+	}
+
+	c, err = ParseOptions{BareKeysAsBool: true}.ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = c.section("s")
+	if got := s.ValueOf("verbose"); got != "true" {
+		t.Errorf("verbose is %q, want true with BareKeysAsBool", got)
+	}
+	s.Add("verbose", "false")
+	if s.IsBare("verbose") {
+		t.Error("verbose is still bare once given a value")
+	}
+}
+
+func TestGlobalSection(t *testing.T) {
+	c, err := ParseOptions{GlobalSection: "root"}.ParseString("k = 1\n[root]\nk = 2\n[s]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GlobalSectionName(); got != "root" {
+		t.Errorf("global section name is %q, want root", got)
+	}
+	sections := c.SectionsByName("root")
+	if len(sections) != 2 || !sections[0].IsGlobal() || sections[1].IsGlobal() {
+		t.Fatalf("%d sections named root, want the global one and the declared one", len(sections))
+	}
+	if sections[0].ValueOf("k") != "1" || sections[1].ValueOf("k") != "2" {
+		t.Errorf("k is %q and %q, want 1 and 2", sections[0].ValueOf("k"), sections[1].ValueOf("k"))
+	}
+	if got, want := c.String(), "k=1\n[root]\nk=2\n[s]\n"; got != want {
+		t.Errorf("written configuration is %q, want %q", got, want)
+	}
+	if got := len(roundTrip(t, c, ParseOptions{GlobalSection: "root"}).SectionsByName("root")); got != 2 {
+		t.Errorf("%d sections named root read back, want 2", got)
+	}
+
+	var perr *ParseError
+	if _, err := (ParseOptions{DisallowGlobalKeys: true}).ParseString("\nk = 1\n[s]\n"); !errors.As(err, &perr) || perr.Line != 2 {
+		t.Errorf("error is %v, want a *ParseError at line 2", err)
+	}
+	if _, err := (ParseOptions{DisallowGlobalKeys: true}).ParseString("; comment\n[s]\nk = 1\n"); err != nil {
+		t.Errorf("parse without global keys failed: %v", err)
+	}
+}
+
+func TestInlineComments(t *testing.T) {
+	c, err := ParseOptions{AllowInlineComments: true}.ParseString("[s]\na = 1 ; one\nb = 2 # two\nc = x;y#z\nd = \"quoted ; kept\" ; comment\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	tests := map[string][2]string{
+		"a": {"1", "; one"},
+		"b": {"2", "# two"},
+		"c": {"x;y#z", ""},
+		"d": {"quoted ; kept", "; comment"},
+	}
+	for opt, want := range tests {
+		if got := [2]string{s.ValueOf(opt), s.InlineCommentFor(opt)}; got != want {
+			t.Errorf("%s: value and comment are %q, want %q", opt, got, want)
+		}
+	}
+	again := roundTrip(t, c, ParseOptions{AllowInlineComments: true}).section("s")
+	for opt, want := range tests {
+		if got := [2]string{again.ValueOf(opt), again.InlineCommentFor(opt)}; got != want {
+			t.Errorf("%s: value and comment read back are %q, want %q", opt, got, want)
+		}
+	}
+
+	c, err = ParseString("[s]\na = 1 ; one\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.section("s").ValueOf("a"); got != "1 ; one" {
+		t.Errorf("a is %q, want the comment kept in the value by default", got)
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	c, err := ParseOptions{AllowLineContinuation: true}.ParseString("[s]\nk = one \\\n    two \\\n  three\nnext = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	if got := s.ValueOf("k"); got != "one two three" {
+		t.Errorf("k is %q, want the joined lines", got)
+	}
+	if got := s.ValueOf("next"); got != "1" {
+		t.Errorf("next is %q, want 1", got)
+	}
+}
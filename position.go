@@ -0,0 +1,70 @@
+package goini
+
+import "strconv"
+
+// Position is the location of a section header or an option line in a parsed file.
+type Position struct {
+	File string // path of the file, empty when parsing from a reader
+	Line int    // 1-based line number, zero if the position is unknown
+}
+
+// IsValid returns true if the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "file:line", "line" without file, or "-" if unknown.
+func (p Position) String() string {
+	switch {
+	case !p.IsValid():
+		return "-"
+	case p.File == "":
+		return strconv.Itoa(p.Line)
+	}
+	return p.File + ":" + strconv.Itoa(p.Line)
+}
+
+// Position returns the position of the section header, or of the first line of the
+// global section, in the file it was parsed from.
+func (s *Section) Position() Position {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.position
+}
+
+// PositionOf returns the position of the line that set the value of the specified
+// option, or the first one for options with several values.
+func (s *Section) PositionOf(option string) Position {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.positions[s.key(option)]
+}
+
+// Position returns the position of the line that set the value of the option, see Section.PositionOf.
+func (k *Key) Position() Position {
+	return k.sec().PositionOf(k.name)
+}
+
+// position returns the position of the line being parsed.
+func (p *parser) position() Position {
+	return Position{File: p.filePath, Line: p.lineNo}
+}
+
+// setPosition records the line being parsed as setting the value of option in the
+// active section, unless it is an additional value.
+func (p *parser) setPosition(option string) {
+	s := p.active
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	option = s.key(option)
+	if len(s.shadows[option]) > 0 {
+		return
+	}
+	if s.positions == nil {
+		s.positions = make(map[string]Position)
+	}
+	s.positions[option] = p.position()
+}
@@ -0,0 +1,77 @@
+package goini
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+)
+
+// ReloadIfChanged parses the file of the configuration again, with the same parse
+// options, if it changed since it was parsed or saved, replacing the sections of
+// the configuration, and returns whether it did. The modification time and size of
+// the file are checked first, the file is read and its content compared only if
+// either differs. Files included by the configuration are not checked. A new
+// snapshot is published if one was taken, see Snapshot.
+// It returns ErrNoFilePath if the configuration has no file path.
+func (c *IniFile) ReloadIfChanged() (bool, error) {
+	if c.Frozen() {
+		return false, ErrFrozen
+	}
+	c.mutex.RLock()
+	filePath, opts := c.filePath, c.parseOptions
+	loaded, loadedSum := c.loaded, c.loadedSum
+	c.mutex.RUnlock()
+	if filePath == "" {
+		return false, ErrNoFilePath
+	}
+
+	state, err := statFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	if state == loaded {
+		return false, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	if sum == loadedSum {
+		c.mutex.Lock()
+		c.loaded = state
+		c.mutex.Unlock()
+		return false, nil
+	}
+
+	parsed, err := opts.parse(bytes.NewReader(data), filePath, nil)
+	if err != nil {
+		return false, err
+	}
+	parsed.loaded, parsed.loadedSum = state, sum
+	before := c.subscribedValues()
+	c.replace(parsed)
+	c.notifyChanges(before)
+	return true, nil
+}
+
+// replace replaces the sections of the configuration, and what was recorded when
+// parsing it, with those of parsed, and publishes a new snapshot if one was taken,
+// see Snapshot. The settings of the configuration are kept.
+func (c *IniFile) replace(parsed *IniFile) {
+	for _, s := range parsed.Sections() {
+		s.file = c
+	}
+
+	c.mutex.Lock()
+	c.sections, c.orderedSections = parsed.sections, parsed.orderedSections
+	c.newline, c.encoding, c.raws = parsed.newline, parsed.encoding, parsed.raws
+	c.loaded, c.loadedSum = parsed.loaded, parsed.loadedSum
+	c.modified, c.restructured = nil, false
+	c.history, c.redo = nil, nil
+	c.mutex.Unlock()
+
+	if c.snapshot.Load() != nil {
+		c.Publish()
+	}
+}
@@ -0,0 +1,76 @@
+package goini
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadIfChangedPublishesSnapshot(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "reload.ini")
+	if err := os.WriteFile(filePath, []byte("[server]\nport = 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := c.Snapshot().StringValueSafe("server", "port"); port != "8080" {
+		t.Fatalf("port is %q before reload, want 8080", port)
+	}
+
+	if err := os.WriteFile(filePath, []byte("[server]\nport = 9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := c.ReloadIfChanged()
+	if err != nil || !reloaded {
+		t.Fatalf("ReloadIfChanged() = %v, %v, want true, nil", reloaded, err)
+	}
+	if port := c.Snapshot().StringValueSafe("server", "port"); port != "9090" {
+		t.Errorf("port is %q in the snapshot after reload, want 9090", port)
+	}
+}
+
+func TestReloadIfChangedNotifies(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "reload.ini")
+	if err := os.WriteFile(filePath, []byte("[server]\nport = 8080\n[remote \"b\"]\nurl = b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := c.SubscribeSubsection("remote", "b", "url")
+	defer c.Unsubscribe(ch)
+	all := c.SubscribeChanges()
+	defer c.UnsubscribeChanges(all)
+
+	if err := os.WriteFile(filePath, []byte("[server]\nport = 9090\n[remote \"b\"]\nurl = b2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := c.ReloadIfChanged(); err != nil || !reloaded {
+		t.Fatalf("ReloadIfChanged() = %v, %v, want true, nil", reloaded, err)
+	}
+
+	want := ValueChange{Section: "remote", SubName: "b", Option: "url", OldValue: "b", NewValue: "b2", Existed: true, Exists: true}
+	if got := receive(t, ch); got != want {
+		t.Errorf("change is %+v, want %+v", got, want)
+	}
+	select {
+	case changes := <-all:
+		if len(changes) != 2 {
+			t.Errorf("changes are %+v, want the changes of port and url", changes)
+		}
+	default:
+		t.Error("no changes received")
+	}
+}
@@ -0,0 +1,166 @@
+package goini
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrHTTPStatus is wrapped by the error ParseURL returns when the server replies with
+// another status than 200 OK, or 304 Not Modified when refreshing.
+var ErrHTTPStatus = errors.New("unexpected HTTP status")
+
+// URLOptions controls how ParseURL fetches a configuration.
+type URLOptions struct {
+	// ParseOptions are the options the configuration is parsed with. Includes are
+	// never processed.
+	ParseOptions ParseOptions
+
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Timeout limits each request, 30 seconds if zero.
+	Timeout time.Duration
+
+	// Refresh is the interval at which the configuration is fetched again until the
+	// context given to ParseURL is done, never if zero. Requests are conditional on
+	// the ETag and Last-Modified headers of the last response, and the configuration
+	// is replaced only if its content changed, like ReloadIfChanged does: a new
+	// snapshot is published if one was taken, and changes made to the configuration
+	// since it was fetched, along with its undo history, are discarded.
+	Refresh time.Duration
+
+	// OnRefresh is called after each refresh that replaced the configuration, with a
+	// nil error, or that failed.
+	OnRefresh func(c *IniFile, err error)
+}
+
+// defaultURLTimeout is used when URLOptions.Timeout is zero.
+const defaultURLTimeout = 30 * time.Second
+
+// remote is a configuration fetched over HTTP with the validators of the last response.
+type remote struct {
+	url          string
+	opts         URLOptions
+	etag         string
+	lastModified string
+	sum          [sha256.Size]byte // SHA-256 of the configuration last parsed
+}
+
+// ParseURL fetches the configuration at rawURL over HTTP or HTTPS and parses it. The
+// returned configuration has no file path. With URLOptions.Refresh, it is refreshed
+// in the background until ctx is done.
+func ParseURL(ctx context.Context, rawURL string, opts URLOptions) (*IniFile, error) {
+	opts.ParseOptions.AllowIncludes = false
+	r := &remote{url: rawURL, opts: opts}
+	data, err := r.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := opts.ParseOptions.parse(bytes.NewReader(data), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	r.sum = sha256.Sum256(data)
+	if opts.Refresh > 0 {
+		go r.refresh(ctx, c)
+	}
+	return c, nil
+}
+
+// fetch returns the content of the configuration, or nil if it was not modified
+// since the last response.
+func (r *remote) fetch(ctx context.Context) ([]byte, error) {
+	timeout := r.opts.Timeout
+	if timeout == 0 {
+		timeout = defaultURLTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	if r.lastModified != "" {
+		req.Header.Set("If-Modified-Since", r.lastModified)
+	}
+	client := r.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && (r.etag != "" || r.lastModified != ""):
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: %s: %s", ErrHTTPStatus, r.url, resp.Status)
+	}
+	body := io.Reader(resp.Body)
+	if maxBytes := r.opts.ParseOptions.MaxBytes; maxBytes > 0 {
+		body = io.LimitReader(body, maxBytes+1) // enough for the parse to fail
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	r.etag, r.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	return data, nil
+}
+
+// refresh fetches the configuration every URLOptions.Refresh until ctx is done,
+// replacing the sections of c when it changed.
+func (r *remote) refresh(ctx context.Context, c *IniFile) {
+	ticker := time.NewTicker(r.opts.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := r.reload(ctx, c)
+		if (changed || err != nil) && r.opts.OnRefresh != nil {
+			r.opts.OnRefresh(c, err)
+		}
+	}
+}
+
+// reload fetches the configuration and replaces the sections of c if it changed,
+// returning whether it did.
+func (r *remote) reload(ctx context.Context, c *IniFile) (bool, error) {
+	if c.Frozen() {
+		return false, ErrFrozen
+	}
+	data, err := r.fetch(ctx)
+	if err != nil || data == nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	if sum == r.sum {
+		return false, nil
+	}
+	parsed, err := r.opts.ParseOptions.parse(bytes.NewReader(data), "", nil)
+	if err != nil {
+		return false, err
+	}
+	r.sum = sum
+	before := c.subscribedValues()
+	c.replace(parsed)
+	c.notifyChanges(before)
+	return true, nil
+}
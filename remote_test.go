@@ -0,0 +1,46 @@
+package goini
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseURLRefreshPublishesSnapshot(t *testing.T) {
+	var port atomic.Value
+	port.Store("8080")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[server]\nport = " + port.Load().(string) + "\n"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	refreshed := make(chan error, 1)
+	c, err := ParseURL(ctx, server.URL, URLOptions{
+		Refresh:   10 * time.Millisecond,
+		OnRefresh: func(c *IniFile, err error) { refreshed <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Snapshot().StringValueSafe("server", "port"); got != "8080" {
+		t.Fatalf("port is %q before refresh, want 8080", got)
+	}
+
+	port.Store("9090")
+	select {
+	case err := <-refreshed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not refreshed")
+	}
+	if got := c.Snapshot().StringValueSafe("server", "port"); got != "9090" {
+		t.Errorf("port is %q in the snapshot after refresh, want 9090", got)
+	}
+}
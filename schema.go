@@ -0,0 +1,351 @@
+package goini
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType is the type of the value of an option declared in a Schema.
+type ValueType int
+
+const (
+	// TypeString accepts any value.
+	TypeString ValueType = iota
+	// TypeInt accepts decimal integers.
+	TypeInt
+	// TypeFloat accepts decimal numbers.
+	TypeFloat
+	// TypeBool accepts the values parsed by Section.Bool.
+	TypeBool
+	// TypeDuration accepts the values parsed by time.ParseDuration.
+	TypeDuration
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeInt:
+		return "integer"
+	case TypeFloat:
+		return "number"
+	case TypeBool:
+		return "boolean"
+	case TypeDuration:
+		return "duration"
+	}
+	return "string"
+}
+
+// Schema declares the sections and options expected in a configuration, see
+// IniFile.Validate. Sections and options not declared are not checked, unless
+// DisallowUnknown is called.
+type Schema struct {
+	sections []*SectionSchema
+	strict   bool
+}
+
+// SectionSchema declares a section of a Schema and its options.
+type SectionSchema struct {
+	name     string
+	required bool
+	keys     []*KeySchema
+}
+
+// KeySchema declares an option of a SectionSchema.
+type KeySchema struct {
+	name       string
+	typ        ValueType
+	required   bool
+	ranged     bool
+	min, max   float64
+	enum       []string
+	def        string
+	hasDefault bool
+	aliases    []string
+}
+
+// NewSchema returns an empty schema.
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Section declares the section name, or returns it if already declared.
+func (schema *Schema) Section(name string) *SectionSchema {
+	for _, s := range schema.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	s := &SectionSchema{name: name}
+	schema.sections = append(schema.sections, s)
+	return s
+}
+
+// DisallowUnknown makes Validate report the sections and options not declared in
+// the schema, such as misspelled ones.
+func (schema *Schema) DisallowUnknown() *Schema {
+	schema.strict = true
+	return schema
+}
+
+// Required makes the section mandatory.
+func (s *SectionSchema) Required() *SectionSchema {
+	s.required = true
+	return s
+}
+
+// Key declares the option name of type typ in the section, or returns it if already
+// declared, its type being set to typ.
+func (s *SectionSchema) Key(name string, typ ValueType) *KeySchema {
+	for _, k := range s.keys {
+		if k.name == name {
+			k.typ = typ
+			return k
+		}
+	}
+	k := &KeySchema{name: name, typ: typ}
+	s.keys = append(s.keys, k)
+	return k
+}
+
+// Required makes the option mandatory, unless it has a default value.
+func (k *KeySchema) Required() *KeySchema {
+	k.required = true
+	return k
+}
+
+// Range restricts the value of a numeric option to [min, max]. Durations are
+// compared in seconds.
+func (k *KeySchema) Range(min, max float64) *KeySchema {
+	k.ranged, k.min, k.max = true, min, max
+	return k
+}
+
+// Enum restricts the value of the option to values.
+func (k *KeySchema) Enum(values ...string) *KeySchema {
+	k.enum = values
+	return k
+}
+
+// Alias declares former names of the option. Validate reports their use as
+// deprecated, and IniFile.UseAliases makes lookups of the option fall back to them.
+func (k *KeySchema) Alias(names ...string) *KeySchema {
+	k.aliases = append(k.aliases, names...)
+	return k
+}
+
+// Default sets the value used for the option when it is missing, see IniFile.ApplyDefaults.
+func (k *KeySchema) Default(value string) *KeySchema {
+	k.def, k.hasDefault = value, true
+	return k
+}
+
+// Violation is a mismatch between a configuration and a Schema.
+type Violation struct {
+	Section     string
+	Option      string   // empty for violations of a whole section
+	Position    Position // position of the option or section, if known
+	Msg         string
+	Replacement string // name to use instead of Option if it is a deprecated alias
+}
+
+// Deprecated returns true if the violation is the use of a deprecated alias, a
+// warning rather than an error.
+func (v Violation) Deprecated() bool {
+	return v.Replacement != ""
+}
+
+func (v Violation) Error() string {
+	var where string
+	if v.Option != "" {
+		where = fmt.Sprintf("%s in section %s", v.Option, v.Section)
+	} else {
+		where = "section " + v.Section
+	}
+	if v.Position.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", v.Position, where, v.Msg)
+	}
+	return where + ": " + v.Msg
+}
+
+// Validate checks the configuration against schema and returns every violation
+// found, in the order of the schema. Values are looked up like ValueOk does.
+func (c *IniFile) Validate(schema *Schema) []Violation {
+	var violations []Violation
+	for _, ss := range schema.sections {
+		s := c.section(ss.name)
+		if s == nil {
+			if ss.required {
+				violations = append(violations, Violation{Section: ss.name, Msg: "missing section"})
+			}
+			for _, k := range ss.keys {
+				if k.required && !k.hasDefault {
+					violations = append(violations, Violation{Section: ss.name, Option: k.name, Msg: "missing option"})
+				}
+			}
+			continue
+		}
+		for _, k := range ss.keys {
+			value, ok := s.ValueOk(k.name)
+			for _, alias := range k.aliases {
+				if !s.Exists(alias) {
+					continue
+				}
+				violations = append(violations, Violation{Section: ss.name, Option: alias, Position: s.PositionOf(alias),
+					Msg: "deprecated, use " + k.name, Replacement: k.name})
+				if !ok {
+					value, ok = s.ValueOk(alias)
+				}
+			}
+			if !ok {
+				if k.required && !k.hasDefault {
+					violations = append(violations, Violation{Section: ss.name, Option: k.name, Position: s.Position(), Msg: "missing option"})
+				}
+				continue
+			}
+			if msg := k.check(value); msg != "" {
+				violations = append(violations, Violation{Section: ss.name, Option: k.name, Position: s.PositionOf(k.name), Msg: msg})
+			}
+		}
+	}
+	if schema.strict {
+		known := make(map[string]map[string]bool, len(schema.sections))
+		for _, ss := range schema.sections {
+			known[ss.name] = make(map[string]bool, len(ss.keys))
+			for _, k := range ss.keys {
+				known[ss.name][k.name] = true
+				for _, alias := range k.aliases {
+					known[ss.name][alias] = true
+				}
+			}
+		}
+		violations = append(violations, c.unknownKeys(known)...)
+	}
+	return violations
+}
+
+// unknownKeys returns a violation for every section missing in known and every
+// option missing in known[section], names matching regardless of case if the
+// configuration ignores case. The global section is only reported if it has options.
+func (c *IniFile) unknownKeys(known map[string]map[string]bool) []Violation {
+	ignoreCase := c.IgnoresCase()
+	var violations []Violation
+	for _, s := range c.Sections() {
+		name := s.Name()
+		options, ok := lookupKnown(known, name, ignoreCase)
+		if !ok {
+			if !s.global {
+				violations = append(violations, Violation{Section: name, Position: s.Position(), Msg: "unknown section"})
+				continue
+			}
+		}
+		for _, opt := range s.OptionNames() {
+			if _, ok := lookupKnown(options, opt, ignoreCase); !ok {
+				violations = append(violations, Violation{Section: name, Option: opt, Position: s.PositionOf(opt), Msg: "unknown option"})
+			}
+		}
+	}
+	return violations
+}
+
+// lookupKnown returns the value of name in known and whether it is there, the first
+// key equal to name regardless of case being used if there is none and ignoreCase is
+// true.
+func lookupKnown[V any](known map[string]V, name string, ignoreCase bool) (V, bool) {
+	if value, ok := known[name]; ok || !ignoreCase {
+		return value, ok
+	}
+	for key, value := range known {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// check returns why value does not match the declaration of the option, or "".
+func (k *KeySchema) check(value string) string {
+	var number float64
+	trimmed := strings.TrimSpace(value)
+	switch k.typ {
+	case TypeInt:
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid %s %q", k.typ, value)
+		}
+		number = float64(n)
+	case TypeFloat:
+		n, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid %s %q", k.typ, value)
+		}
+		number = n
+	case TypeBool:
+		if _, err := parseBool(value); err != nil {
+			return fmt.Sprintf("invalid %s %q", k.typ, value)
+		}
+	case TypeDuration:
+		d, err := time.ParseDuration(trimmed)
+		if err != nil {
+			return fmt.Sprintf("invalid %s %q", k.typ, value)
+		}
+		number = d.Seconds()
+	}
+	if k.ranged && (k.typ == TypeInt || k.typ == TypeFloat || k.typ == TypeDuration) &&
+		(number < k.min || number > k.max) {
+		return fmt.Sprintf("%s out of range [%g, %g]", value, k.min, k.max)
+	}
+	if len(k.enum) > 0 && !slices.Contains(k.enum, value) {
+		return fmt.Sprintf("%q is not one of %q", value, k.enum)
+	}
+	return ""
+}
+
+// UseAliases makes lookups of the options declared in schema fall back to the
+// values of their aliases, see KeySchema.Alias, when they are missing.
+func (c *IniFile) UseAliases(schema *Schema) {
+	aliases := make(map[string][]string)
+	for _, ss := range schema.sections {
+		for _, k := range ss.keys {
+			if len(k.aliases) > 0 {
+				aliases[optionKey(ss.name, k.name)] = slices.Clone(k.aliases)
+			}
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.aliases = aliases
+}
+
+// aliasesOf returns the aliases of option in section, see UseAliases.
+func (c *IniFile) aliasesOf(section, option string) []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.aliases[optionKey(section, option)]
+}
+
+// ApplyDefaults sets the options declared with a default value in schema that are
+// missing in the configuration, adding their sections if needed.
+func (c *IniFile) ApplyDefaults(schema *Schema) error {
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	for _, ss := range schema.sections {
+		for _, k := range ss.keys {
+			if !k.hasDefault {
+				continue
+			}
+			s := c.sectionOrAdd(ss.name)
+			if _, ok := s.ValueOk(k.name); !ok {
+				s.Add(k.name, k.def)
+			}
+		}
+	}
+	return nil
+}
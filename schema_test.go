@@ -0,0 +1,20 @@
+package goini
+
+import "testing"
+
+func TestValidateStrictIgnoreCase(t *testing.T) {
+	c, err := ParseOptions{IgnoreCase: true}.ParseString("[Server]\nPort = 8080\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := NewSchema().DisallowUnknown()
+	schema.Section("server").Key("port", TypeInt).Required()
+	if violations := c.Validate(schema); len(violations) != 0 {
+		t.Errorf("Validate() = %v, want no violations", violations)
+	}
+
+	c.SetIgnoreCase(false)
+	if violations := c.Validate(schema); len(violations) == 0 {
+		t.Error("Validate() reported no violation with case-sensitive names")
+	}
+}
@@ -1,16 +1,84 @@
 package goini
 
 import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
-
 type Section struct {
-	name string
-	options map[string]string
-	mutex sync.RWMutex
-	orderedOptions []string
+	file            *IniFile // configuration the section belongs to, if any
+	name            string
+	subName         string // subsection name of a `[name "subsection"]` header
+	options         map[string]string
+	mutex           sync.RWMutex
+	orderedOptions  []string
+	inlineComments  map[string]string
+	comments        map[string]string   // comment lines preceding an option
+	comment         string              // comment lines preceding the section header
+	headerComment   string              // inline comment following the section header
+	trailingComment string              // comment lines after the last option
+	parent          string              // name of the section options are inherited from
+	shadows         map[string][]string // additional values of options appearing several times
+	raw             *rawSection         // lines as parsed, see ParseOptions.PreserveFormatting
+	position        Position            // position of the header
+	positions       map[string]Position // positions of the option lines
+	bare            map[string]string   // values of the options declared without delimiter, see IsBare
+	resets          map[string]bool     // systemd options reset by an empty assignment, see DialectSystemd
+	global          bool                // holds the options preceding the first header, written without header
+}
+
+// Clone returns a deep copy of the section, with its options, order and comments,
+// that is not part of any configuration.
+func (s *Section) Clone() *Section {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	clone := &Section{
+		name:            s.name,
+		subName:         s.subName,
+		options:         maps.Clone(s.options),
+		orderedOptions:  slices.Clone(s.orderedOptions),
+		inlineComments:  maps.Clone(s.inlineComments),
+		comments:        maps.Clone(s.comments),
+		comment:         s.comment,
+		headerComment:   s.headerComment,
+		trailingComment: s.trailingComment,
+		parent:          s.parent,
+		raw:             s.raw.clone(),
+		position:        s.position,
+		positions:       maps.Clone(s.positions),
+		bare:            maps.Clone(s.bare),
+		resets:          maps.Clone(s.resets),
+	}
+	if s.shadows != nil {
+		clone.shadows = make(map[string][]string, len(s.shadows))
+		for opt, values := range s.shadows {
+			clone.shadows[opt] = slices.Clone(values)
+		}
+	}
+	return clone
+}
+
+// key returns the spelling of option in the section, option itself if it is
+// missing or case matters, see IniFile.SetIgnoreCase. The caller must hold the lock.
+func (s *Section) key(option string) string {
+	if s.file == nil || !s.file.ignoreCase.Load() {
+		return option
+	}
+	if _, ok := s.options[option]; ok {
+		return option
+	}
+	for _, opt := range s.orderedOptions {
+		if strings.EqualFold(opt, option) {
+			return opt
+		}
+	}
+	return option
 }
 
 // Name returns the name of the section
@@ -21,120 +89,976 @@ func (s *Section) Name() string {
 	return s.name
 }
 
+// SubName returns the subsection name of a `[name "subsection"]` section, or an empty string.
+func (s *Section) SubName() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.subName
+}
+
+// IsGlobal returns true if s is the global section, holding the options preceding
+// the first section header, rather than a section declared with a header of the same
+// name.
+func (s *Section) IsGlobal() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.global
+}
+
 // Exists returns true if the option exists
 func (s *Section) Exists(option string) (ok bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
+	option = s.key(option)
+
 	_, ok = s.options[option]
 	return
 }
 
-// ValueOf returns the value of specified option.
-func (s *Section) ValueOf(option string) string {
+// IsSet returns true if the option exists with a value, even an empty one as in
+// "key =", rather than as a bare "key".
+func (s *Section) IsSet(option string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	option = s.key(option)
+	_, ok := s.options[option]
+	return ok && !s.isBare(option)
+}
+
+// IsBare returns true if the option is declared without delimiter nor value, as
+// flags are. Its value is empty, or "true" with ParseOptions.BareKeysAsBool, and it
+// is written back without value unless the value changes.
+func (s *Section) IsBare(option string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.isBare(s.key(option))
+}
+
+// isBare returns true if option is declared without delimiter and keeps the value
+// it was given. The caller must hold the lock.
+func (s *Section) isBare(option string) bool {
+	value, ok := s.bare[option]
+	return ok && value == s.options[option] && len(s.shadows[option]) == 0
+}
+
+// addBare adds option declared without delimiter with value, see IsBare.
+func (s *Section) addBare(option, value string) {
+	s.Add(option, value)
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if s.bare == nil {
+		s.bare = make(map[string]string)
+	}
+	s.bare[s.key(option)] = value
+}
+
+// ValueOf returns the value of specified option.
+func (s *Section) ValueOf(option string) string {
+	value, _ := s.ValueOk(option)
+	return value
+}
+
+// ValueOfRaw returns the value of specified option as written in the configuration,
+// without expanding environment variables.
+func (s *Section) ValueOfRaw(option string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	option = s.key(option)
+
 	return s.options[option]
 }
 
+// ValueOfWithDefaults returns the value of specified option, falling back to the
+// value of the DEFAULT section if the option does not exist in the section.
+func (s *Section) ValueOfWithDefaults(option string) string {
+	value, _ := s.resolve(option, true)
+	return value
+}
+
+// ValueOk returns the value of specified option and whether the option exists.
+func (s *Section) ValueOk(option string) (string, bool) {
+	return s.resolve(option, s.file != nil && s.file.UsesDefaults())
+}
+
+// LocalizedValue returns the value of the "option[locale]" key best matching locale,
+// as defined by the XDG Desktop Entry specification, or the value of option if there
+// is none. locale has the form lang_COUNTRY.ENCODING@MODIFIER where every part but
+// lang is optional; the encoding is ignored. Keys are tried in the order
+// option[lang_COUNTRY@MODIFIER], option[lang_COUNTRY], option[lang@MODIFIER], option[lang].
+func (s *Section) LocalizedValue(option, locale string) string {
+	var modifier string
+	if i := strings.IndexByte(locale, '@'); i != -1 {
+		locale, modifier = locale[:i], locale[i+1:]
+	}
+	if i := strings.IndexByte(locale, '.'); i != -1 {
+		locale = locale[:i]
+	}
+	lang, country, _ := strings.Cut(locale, "_")
+
+	var candidates []string
+	if country != "" && modifier != "" {
+		candidates = append(candidates, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		candidates = append(candidates, lang+"_"+country)
+	}
+	if modifier != "" {
+		candidates = append(candidates, lang+"@"+modifier)
+	}
+	if lang != "" {
+		candidates = append(candidates, lang)
+	}
+	for _, candidate := range candidates {
+		if value, ok := s.ValueOk(option + "[" + candidate + "]"); ok {
+			return value
+		}
+	}
+	return s.ValueOf(option)
+}
+
+// value returns the value of the specified option or an error if the option does not exist.
+func (s *Section) value(option string) (string, error) {
+	value, ok := s.ValueOk(option)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrOptionNotFound, option)
+	}
+	return value, nil
+}
+
+// envName returns the section name passed to EnvMapper, empty for the global section.
+func (s *Section) envName() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.global {
+		return ""
+	}
+	return s.name
+}
+
+// resolve returns the value of the specified option, overridden by an environment
+// variable if enabled, looking it up in the DEFAULT section if defaults is true,
+// with environment variables expanded if enabled.
+func (s *Section) resolve(option string, defaults bool) (string, bool) {
+	if s.file != nil {
+		if value, ok := s.file.envOverride(s.envName(), option); ok {
+			return value, true
+		}
+	}
+	value, ok := s.lookup(option, defaults)
+	if !ok && s.file != nil {
+		for _, alias := range s.file.aliasesOf(s.Name(), option) {
+			if value, ok = s.lookup(alias, defaults); ok {
+				break
+			}
+		}
+	}
+	if ok && s.file != nil && s.file.ExpandsEnv() {
+		value = expandEnv(value)
+	}
+	return value, ok
+}
+
+// lookup returns the raw value of the specified option, looking it up through the
+// inheritance chain and in the DEFAULT section if defaults is true.
+func (s *Section) lookup(option string, defaults bool) (string, bool) {
+	var visited []*Section // sections of the inheritance chain, allocated only when there is one
+	for section := s; section != nil && !slices.Contains(visited, section); {
+		if section != s || s.parent != "" {
+			visited = append(visited, section)
+		}
+
+		section.mutex.RLock()
+		value, ok := section.options[section.key(option)]
+		parent := section.parent
+		section.mutex.RUnlock()
+
+		if ok {
+			return value, true
+		}
+		if parent == "" || s.file == nil {
+			break
+		}
+		section = s.file.section(parent)
+	}
+
+	if defaults && s.file != nil && s.name != DefaultSectionName {
+		if d := s.file.section(DefaultSectionName); d != nil {
+			return d.lookup(option, false)
+		}
+	}
+	return "", false
+}
+
+// Parent returns the name of the section options are inherited from, if any.
+func (s *Section) Parent() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.parent
+}
+
+// HeaderText returns the text between the brackets of the header of the section,
+// like `remote "origin"` or `child : base`, with names quoted as they are written.
+// ParseHeaderText reads it back.
+func (s *Section) HeaderText() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return sectionHeaderText(s)
+}
+
+// ParseHeaderText returns the section name, subsection name and parent of the text
+// between the brackets of a section header, as returned by Section.HeaderText. A colon
+// outside of quotes separates the parent, as with ParseOptions.AllowInheritance.
+func ParseHeaderText(text string) (name, subName, parent string) {
+	return parseSectionHeader("["+text+"]", true)
+}
+
+// SetParent sets the name of the section options missing in s are inherited from.
+// The section is written with a "[name : parent]" header, read back as such with
+// ParseOptions.AllowInheritance. An empty name removes the parent.
+func (s *Section) SetParent(parent string) {
+	if s.frozen() {
+		return
+	}
+	s.mutex.Lock()
+	s.parent = parent
+	s.mutex.Unlock()
+
+	s.touch()
+}
+
 // SetValueFor sets the value for the specified option and returns the old value.
 func (s *Section) SetValueFor(option string, value string) string {
+	if s.frozen() {
+		return s.ValueOfRaw(option)
+	}
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	var oldValue string
-	oldValue, s.options[option] = s.options[option], value
+	option = s.key(option)
+	oldValue, existed := s.options[option]
+	s.options[option] = value
+	delete(s.shadows, option)
+	s.mutex.Unlock()
 
+	s.changed(option, oldValue, existed, value, true)
 	return oldValue
 }
 
 // Add adds a new option to the section. Adding and existing option will overwrite the old one.
 // The old value is returned
 func (s *Section) Add(option string, value string) (oldValue string) {
+	if s.frozen() {
+		return s.ValueOfRaw(option)
+	}
+	option, oldValue, existed := s.setOption(option, value)
+	s.changed(option, oldValue, existed, value, true)
+	return oldValue
+}
+
+// setOption sets the value of option, adding it if it does not exist, and returns
+// the name it is stored under, its previous value and whether it existed.
+func (s *Section) setOption(option, value string) (string, string, bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	var ok bool
-	if oldValue, ok = s.options[option]; !ok {
+	option = s.key(option)
+	oldValue, existed := s.options[option]
+	if !existed {
 		s.orderedOptions = append(s.orderedOptions, option)
 	}
 	s.options[option] = value
+	delete(s.shadows, option)
+	return option, oldValue, existed
+}
 
-	return oldValue
+// Rename renames the option oldName to newName, keeping its position, values and comments.
+func (s *Section) Rename(oldName, newName string) error {
+	if s.frozen() {
+		return ErrFrozen
+	}
+	value, err := s.rename(oldName, newName)
+	if err != nil {
+		return err
+	}
+	s.renamed(oldName, newName, value)
+	return nil
+}
+
+// rename renames the option oldName to newName and returns its value.
+func (s *Section) rename(oldName, newName string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	oldName = s.key(oldName)
+	if _, ok := s.options[oldName]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrOptionNotFound, oldName)
+	}
+	if existing := s.key(newName); existing != oldName || newName == oldName {
+		if _, ok := s.options[existing]; ok {
+			return "", fmt.Errorf("%w: %s", ErrOptionExists, newName)
+		}
+	}
+	s.options[newName] = s.options[oldName]
+	delete(s.options, oldName)
+	if comment, ok := s.comments[oldName]; ok {
+		s.comments[newName] = comment
+		delete(s.comments, oldName)
+	}
+	if comment, ok := s.inlineComments[oldName]; ok {
+		s.inlineComments[newName] = comment
+		delete(s.inlineComments, oldName)
+	}
+	if shadows, ok := s.shadows[oldName]; ok {
+		s.shadows[newName] = shadows
+		delete(s.shadows, oldName)
+	}
+	if position, ok := s.positions[oldName]; ok {
+		s.positions[newName] = position
+		delete(s.positions, oldName)
+	}
+	if value, ok := s.bare[oldName]; ok {
+		s.bare[newName] = value
+		delete(s.bare, oldName)
+	}
+	if s.resets[oldName] {
+		s.resets[newName] = true
+		delete(s.resets, oldName)
+	}
+	for i, opt := range s.orderedOptions {
+		if opt == oldName {
+			s.orderedOptions[i] = newName
+		}
+	}
+	return s.options[newName], nil
+}
+
+// MoveOptionBefore moves option just before the option mark.
+func (s *Section) MoveOptionBefore(option, mark string) error {
+	return s.moveOption(option, mark, false)
+}
+
+// MoveOptionAfter moves option just after the option mark.
+func (s *Section) MoveOptionAfter(option, mark string) error {
+	return s.moveOption(option, mark, true)
+}
+
+func (s *Section) moveOption(option, mark string, after bool) error {
+	if s.frozen() {
+		return ErrFrozen
+	}
+	s.mutex.Lock()
+	option = s.key(option)
+	mark = s.key(mark)
+	var ok bool
+	s.orderedOptions, ok = moveItem(s.orderedOptions, option, mark, after)
+	missing := indexOf(s.orderedOptions, option) == -1
+	s.mutex.Unlock()
+
+	if !ok {
+		if missing {
+			return fmt.Errorf("%w: %s", ErrOptionNotFound, option)
+		}
+		return fmt.Errorf("%w: %s", ErrOptionNotFound, mark)
+	}
+	s.touch()
+	return nil
+}
+
+// AddShadow adds another value to the specified option, which then appears once per value
+// when the section is written. The option is added if it does not exist.
+func (s *Section) AddShadow(option string, value string) {
+	if s.frozen() {
+		return
+	}
+	s.mutex.Lock()
+	option = s.key(option)
+	_, existed := s.options[option]
+	if !existed {
+		s.orderedOptions = append(s.orderedOptions, option)
+		s.options[option] = value
+	} else {
+		if s.shadows == nil {
+			s.shadows = make(map[string][]string)
+		}
+		s.shadows[option] = append(s.shadows[option], value)
+	}
+	s.mutex.Unlock()
+
+	if existed {
+		s.touchOption(option)
+	} else {
+		s.changed(option, "", false, value, true)
+	}
+}
+
+// ValuesOf returns all the values of the specified option in order, including the
+// shadow values added by AddShadow. ValueOf returns the first of them.
+func (s *Section) ValuesOf(option string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	option = s.key(option)
+
+	value, ok := s.options[option]
+	if !ok {
+		return nil
+	}
+	return append([]string{value}, s.shadows[option]...)
 }
 
 // Delete removes the specified option from the section and returns the deleted option's value.
 func (s *Section) Delete(option string) (value string) {
+	if s.frozen() {
+		return s.ValueOfRaw(option)
+	}
+	option, value, existed := s.deleteOption(option)
+	s.changed(option, value, existed, "", false)
+	return value
+}
+
+// deleteOption deletes option with its comments and metadata, and returns the name
+// it was stored under, its value and whether it existed.
+func (s *Section) deleteOption(option string) (string, string, bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	value = s.options[option]
+	option = s.key(option)
+	value, existed := s.options[option]
 	delete(s.options, option)
-	for i, opt := range s.orderedOptions {
-		if opt == option {
-			s.orderedOptions = append(s.orderedOptions[:i], s.orderedOptions[i+1:]...)
+	delete(s.inlineComments, option)
+	delete(s.comments, option)
+	delete(s.shadows, option)
+	delete(s.positions, option)
+	delete(s.bare, option)
+	delete(s.resets, option)
+	s.orderedOptions = slices.DeleteFunc(s.orderedOptions, func(opt string) bool { return opt == option })
+	return option, value, existed
+}
+
+// formatComment prefixes every line of comment lacking a comment prefix with the
+// one used when saving, "#" if not set, and a space.
+func (s *Section) formatComment(comment string) string {
+	prefixes, prefix := s.commentStyle()
+	if prefix == "" {
+		prefix = "#"
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		if !isComment(strings.TrimLeft(line, " \t"), prefixes) {
+			lines[i] = prefix + " " + line
 		}
 	}
-	return value
+	return strings.Join(lines, "\n")
 }
 
-// Options returns a map of options for the section.
+// commentStyle returns the prefixes of comments and the prefix added to comments
+// lacking one, see ParseOptions.CommentPrefixes and SaveOptions.CommentPrefix.
+func (s *Section) commentStyle() (prefixes []string, prefix string) {
+	if s.file == nil {
+		return defaultCommentPrefixes, ""
+	}
+	s.file.mutex.RLock()
+	defer s.file.mutex.RUnlock()
+
+	return s.file.parseOptions.commentPrefixes(), s.file.saveOptions.CommentPrefix
+}
+
+// Comment returns the comment lines preceding the section header.
+func (s *Section) Comment() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.comment
+}
+
+// SetComment sets the comment lines written before the section header.
+// Lines not starting with a comment character are prefixed with "# ", see
+// SaveOptions.CommentPrefix.
+func (s *Section) SetComment(comment string) {
+	if s.frozen() {
+		return
+	}
+	if comment != "" {
+		comment = s.formatComment(comment)
+	}
+	s.mutex.Lock()
+	s.comment = comment
+	s.mutex.Unlock()
+
+	s.touch()
+}
+
+// CommentFor returns the comment lines preceding the specified option.
+func (s *Section) CommentFor(option string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	option = s.key(option)
+
+	return s.comments[option]
+}
+
+// SetCommentFor sets the comment lines written before the specified option.
+// Lines not starting with a comment character are prefixed with "# ", see
+// SaveOptions.CommentPrefix. An empty comment removes the comment.
+func (s *Section) SetCommentFor(option string, comment string) {
+	if s.frozen() {
+		return
+	}
+	if comment != "" {
+		comment = s.formatComment(comment)
+	}
+	s.mutex.Lock()
+	option = s.key(option)
+	if comment == "" {
+		delete(s.comments, option)
+	} else {
+		if s.comments == nil {
+			s.comments = make(map[string]string)
+		}
+		s.comments[option] = comment
+	}
+	s.mutex.Unlock()
+
+	s.touch()
+}
+
+// InlineCommentFor returns the inline comment of the specified option, including its comment character.
+func (s *Section) InlineCommentFor(option string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	option = s.key(option)
+
+	return s.inlineComments[option]
+}
+
+// SetInlineCommentFor sets the inline comment written after the value of the specified option.
+// A "; " prefix, or SaveOptions.CommentPrefix, is added if comment does not start
+// with a comment character. An empty comment removes the inline comment.
+func (s *Section) SetInlineCommentFor(option string, comment string) {
+	if s.frozen() {
+		return
+	}
+	if prefixes, prefix := s.commentStyle(); comment != "" && !isComment(comment, prefixes) {
+		if prefix == "" {
+			prefix = ";"
+		}
+		comment = prefix + " " + comment
+	}
+	s.mutex.Lock()
+	option = s.key(option)
+	if comment == "" {
+		delete(s.inlineComments, option)
+	} else {
+		if s.inlineComments == nil {
+			s.inlineComments = make(map[string]string)
+		}
+		s.inlineComments[option] = comment
+	}
+	s.mutex.Unlock()
+
+	s.touch()
+}
+
+// Options returns a copy of the options of the section and their values.
 func (s *Section) Options() map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return maps.Clone(s.options)
+}
+
+// UnsafeOptions returns the map of options of the section without copying it.
+// It must not be modified, nor read while the section may be modified concurrently.
+func (s *Section) UnsafeOptions() map[string]string {
 	return s.options
 }
 
-// OptionNames returns a slice of option names in the same order as they were parsed.
+// OptionNames returns a copy of the option names in the same order as they were parsed.
 func (s *Section) OptionNames() []string {
-	return s.orderedOptions
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return slices.Clone(s.orderedOptions)
+}
+
+// Keys returns an iterator over the options of the section and their values in
+// order. The section is locked while reading each option only, so the loop body
+// may modify it, at the risk of skipping options deleted or moved meanwhile.
+func (s *Section) Keys() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for i := 0; ; i++ {
+			s.mutex.RLock()
+			if i >= len(s.orderedOptions) {
+				s.mutex.RUnlock()
+				return
+			}
+			opt := s.orderedOptions[i]
+			value := s.options[opt]
+			s.mutex.RUnlock()
+
+			if !yield(opt, value) {
+				return
+			}
+		}
+	}
+}
+
+// Range calls f for every option of the section and its value in order, until f
+// returns false. The section is locked for reading meanwhile, f must not modify it.
+func (s *Section) Range(f func(option, value string) bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, opt := range s.orderedOptions {
+		if !f(opt, s.options[opt]) {
+			return
+		}
+	}
 }
 
 // String returns the text representation of a section with its options.
 func (s *Section) String() string {
+	return s.format(SaveOptions{}, DialectINI)
+}
+
+// wrapLine splits line into continuation lines of at most column characters, breaking after spaces.
+func wrapLine(line string, column int) string {
+	var parts []string
+	for len(line) > column {
+		i := column - 2 // room for the trailing backslash
+		for i > 0 && !(line[i-1] == ' ' && line[i] != ' ') {
+			i--
+		}
+		if i <= 0 {
+			break
+		}
+		parts = append(parts, line[:i]+"\\")
+		line = "    " + line[i:]
+	}
+	parts = append(parts, line)
+	return strings.Join(parts, "\n")
+}
+
+// formatOption returns the line of an option with its value in the given dialect,
+// the option name being padded to width characters.
+func formatOption(opt, value string, opts SaveOptions, dialect Dialect, width int) string {
+	delim := opts.Delimiter
+	if delim == "" {
+		delim = "="
+	}
+	if opts.SpaceAroundDelimiter && delim != " " {
+		delim = " " + delim + " "
+	}
+	name := opt
+	if n := utf8.RuneCountInString(opt); n < width {
+		name += strings.Repeat(" ", width-n)
+	}
+	var line string
+	switch {
+	case dialect == DialectSystemd:
+		line = name + delim + value
+	case opts.EscapeValues && value != "":
+		escaped, ok := escapeValue(value)
+		if !ok {
+			escaped = quoteValue(value, opts.QuoteInlineComments)
+		} else if opts.EscapeValueDelims {
+			escaped = valueDelimEscaper.Replace(escaped)
+		}
+		line = name + delim + escaped
+	case opts.IndentedContinuation && canContinue(value, opts):
+		line = name + delim + strings.ReplaceAll(value, "\n", "\n\t")
+	case value != "":
+		quoted := quoteValue(value, opts.QuoteInlineComments)
+		line = name + delim + quoted
+		if opts.EscapeValueDelims && !isQuoted(quoted) && strings.ContainsAny(value, "=:") {
+			if strings.Contains(value, "\\") {
+				line = name + delim + "\"" + escapeQuoted(value) + "\""
+			} else {
+				line = name + delim + valueDelimEscaper.Replace(value)
+			}
+		}
+	default:
+		line = strings.TrimRight(name+delim, " ")
+	}
+	if opts.WrapColumn > 0 {
+		line = wrapLine(line, opts.WrapColumn)
+	}
+	return line
+}
+
+// header returns the header line of the section, without line ending.
+func (s *Section) header() string {
+	if s.subName == "" && s.parent != "" {
+		return "[" + quoteSectionName(s.name) + " : " + quoteSectionName(s.parent) + "]"
+	}
+	return header(s.name, s.subName)
+}
+
+// header returns the header line of a section, without line ending.
+func header(name, subName string) string {
+	if subName != "" {
+		return "[" + quoteSectionName(name) + " \"" + escapeQuoted(subName) + "\"]"
+	}
+	return "[" + quoteSectionName(name) + "]"
+}
+
+// quoteSectionName quotes name if it could not be parsed back from a header otherwise,
+// see parseSectionHeader.
+func quoteSectionName(name string) string {
+	if name != "" && name == strings.TrimSpace(name) && !strings.ContainsAny(name, "]\":\n\r") {
+		return name
+	}
+	return "\"" + escapeQuoted(name) + "\""
+}
+
+// headerLine returns the header line of the section followed by its inline comment,
+// without line ending.
+func (s *Section) headerLine() string {
+	if s.headerComment != "" {
+		return s.header() + " " + s.headerComment
+	}
+	return s.header()
+}
+
+// format returns the text representation of a section using the save options opts and the dialect.
+func (s *Section) format(opts SaveOptions, dialect Dialect) string {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	var parts []string
-	sName := "[" + s.name + "]\n"
-	if s.name == "global" {
-		sName = ""
+	var b strings.Builder
+	b.Grow(s.sizeHint())
+	if s.comment != "" {
+		b.WriteString(s.comment + "\n")
+	}
+	if !s.global {
+		b.WriteString(s.headerLine() + "\n")
 	}
-	parts = append(parts, sName)
 
+	var width int
+	if opts.AlignValues {
+		for _, opt := range s.orderedOptions {
+			width = max(width, utf8.RuneCountInString(opt))
+		}
+	}
 	for _, opt := range s.orderedOptions {
-		value := s.options[opt]
-		if value != "" {
-			parts = append(parts, opt, "=", value, "\n")
+		if opts.OmitEmptyValues && s.options[opt] == "" && len(s.shadows[opt]) == 0 {
+			continue
+		}
+		if comment, ok := s.comments[opt]; ok {
+			b.WriteString(comment + "\n")
+		}
+		if dialect == DialectSystemd && s.resets[opt] && (s.options[opt] != "" || len(s.shadows[opt]) > 0) {
+			b.WriteString(formatOption(opt, "", opts, dialect, width) + "\n") // the reset preceding the values
+		}
+		if s.isBare(opt) {
+			b.WriteString(opt)
 		} else {
-			parts = append(parts, opt, "\n")
+			b.WriteString(formatOption(opt, s.options[opt], opts, dialect, width))
 		}
+		if comment, ok := s.inlineComments[opt]; ok {
+			b.WriteString(" " + comment)
+		}
+		b.WriteByte('\n')
+		for _, value := range s.shadows[opt] {
+			b.WriteString(formatOption(opt, value, opts, dialect, width) + "\n")
+		}
+	}
+	if s.trailingComment != "" {
+		b.WriteString(s.trailingComment + "\n")
 	}
 
-	return strings.Join(parts, "")
+	return b.String()
 }
 
-func parseOption(option string) (opt, value string) {
+// sizeHint returns an estimate of the length of the text representation of the
+// section. The caller must hold the lock.
+func (s *Section) sizeHint() int {
+	n := len(s.comment) + len(s.name) + len(s.subName) + len(s.parent) + len(s.trailingComment) + 8
+	for opt, value := range s.options {
+		n += len(opt) + len(value) + 2
+		for _, shadow := range s.shadows[opt] {
+			n += len(opt) + len(shadow) + 2
+		}
+	}
+	for _, comment := range s.comments {
+		n += len(comment) + 1
+	}
+	return n
+}
 
-	split := func(i int, delim string) (opt, value string) {
-		opt = strings.Trim(option[:i], " ")
-		value = strings.Trim(option[i+1:], " ")
-		return
+// delimiterIndex returns the index of the delimiter separating option and value, or -1.
+// delims are the accepted delimiter characters, see ParseOptions.Delimiters.
+func delimiterIndex(option, delims string) int {
+	if delims == "" {
+		delims = "=:"
 	}
+	start := len(option) - len(strings.TrimLeft(option, " \t"))
+	chars := delims
+	if strings.Contains(delims, " ") {
+		chars += "\t"
+	}
+	i := strings.IndexAny(option[start:], chars)
+	if i == -1 {
+		return -1
+	}
+	i += start
+	if option[i] == ' ' || option[i] == '\t' {
+		// a delimiter following the whitespace takes precedence: "key = value"
+		if j := len(option) - len(strings.TrimLeft(option[i:], " \t")); j < len(option) &&
+			option[j] != ' ' && strings.IndexByte(delims, option[j]) != -1 {
+			return j
+		}
+	}
+	return i
+}
 
-	if i := strings.Index(option, "="); i != -1 {
-		opt, value = split(i, "=")
-	} else if i := strings.Index(option, ":"); i != -1 {
-		opt, value = split(i, ":")
+// parseOption splits an option line into the option name and its unquoted value,
+// using the delimiter characters delims, see delimiterIndex.
+func parseOption(option, delims string) (opt, value string) {
+	if i := delimiterIndex(option, delims); i != -1 {
+		opt = strings.Trim(option[:i], " \t")
+		value = unquoteValue(strings.Trim(option[i+1:], " \t"))
 	} else {
-		opt = option
+		opt = strings.TrimRight(strings.TrimLeft(option, " \t"), " \t\r") // a stray carriage return could not be written back
 	}
 	return
 }
 
-//Section object
-func (s *Section) AddOption(option string){
-	var opt, value string
-	if opt, value = parseOption(option); value != "" {
-		s.options[opt] = value
+// quoteEnd returns the index of the quote closing the quoted value starting at v[0], or -1.
+func quoteEnd(v string) int {
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case v[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+// isQuoted returns true if value is enclosed in single or double quotes.
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && quoteEnd(value) == len(value)-1
+}
+
+// unquoteValue removes the single or double quotes around value and unescapes it.
+// Values that are not quoted are returned unchanged.
+func unquoteValue(value string) string {
+	if !isQuoted(value) {
+		return value
+	}
+	return unescapeValue(value[1 : len(value)-1])
+}
+
+// valueDelimEscaper and valueDelimUnescaper escape the delimiters of unquoted values,
+// see SaveOptions.EscapeValueDelims.
+var (
+	valueDelimEscaper   = strings.NewReplacer("=", "\\=", ":", "\\:")
+	valueDelimUnescaper = strings.NewReplacer("\\=", "=", "\\:", ":")
+)
+
+// unescapeValue replaces the \n, \t, \r, \\, \" and \' escape sequences of a quoted value.
+func unescapeValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\', '"', '\'':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// quoteValue returns value in double quotes with escape sequences if it could not be
+// read back as is, otherwise value is returned unchanged. Values that would be split
+// at an inline comment are quoted if inlineComments is true.
+func quoteValue(value string, inlineComments bool) string {
+	if value == "" || (value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\n\r") &&
+		value[0] != '"' && value[0] != '\'' && !(inlineComments && hasInlineComment(value))) {
+		return value
+	}
+	return "\"" + escapeQuoted(value) + "\""
+}
+
+// quotedEscaper escapes the characters of a quoted value, see unescapeValue.
+var quotedEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r")
+
+// escapeQuoted escapes s to be written between double quotes.
+func escapeQuoted(s string) string {
+	return quotedEscaper.Replace(s)
+}
+
+// canContinue returns true if value holds several lines that can be written as
+// indented continuation lines and read back unchanged, see
+// SaveOptions.IndentedContinuation.
+func canContinue(value string, opts SaveOptions) bool {
+	lines := strings.Split(value, "\n")
+	if len(lines) == 1 || opts.QuoteInlineComments && hasInlineComment(lines[0]) {
+		return false
+	}
+	for _, line := range lines {
+		if line == "" || line != strings.TrimSpace(line) || strings.ContainsAny(line[:1], "\"'") ||
+			isComment(line, opts.commentPrefixList()) ||
+			opts.CommentPrefix != "" && strings.HasPrefix(line, opts.CommentPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasInlineComment returns true if value holds a '#' or ';' that a parser splitting
+// inline comments would take for the start of a comment: one at the start of value,
+// or after whitespace.
+func hasInlineComment(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if (value[i] == '#' || value[i] == ';') && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// AddOption adds the option of an option line, "key = value", "key =" or a bare
+// "key", see IsBare, parsed with the delimiters of the configuration.
+func (s *Section) AddOption(option string) {
+	var opts ParseOptions
+	if s.file != nil {
+		s.file.mutex.RLock()
+		opts = s.file.parseOptions
+		s.file.mutex.RUnlock()
+	}
+	if delimiterIndex(option, opts.Delimiters) == -1 {
+		s.addBare(strings.Trim(option, " \t"), opts.bareValue())
+		return
 	}
+	s.Add(parseOption(option, opts.Delimiters))
 }
@@ -0,0 +1,66 @@
+package goini
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roundTrip saves c to a file, parses it again with opts and returns the result.
+func roundTrip(t *testing.T, c *IniFile, opts ParseOptions) *IniFile {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "roundtrip.ini")
+	if err := c.SaveAs(filePath); err != nil {
+		t.Fatal(err)
+	}
+	again, err := opts.Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return again
+}
+
+func TestValueDelimitersRoundTrip(t *testing.T) {
+	values := map[string]string{
+		"connstr": "host=a;user=b",
+		"spaced":  "host=a ; user=b # not a comment",
+		"url":     "http://h:80/?a=b",
+		"path":    `C:\dir`,
+		"both":    `a=b:c\d`,
+	}
+	cases := map[string]ParseOptions{
+		"default":             {},
+		"EscapeValueDelims":   {EscapeValueDelims: true},
+		"AllowInlineComments": {AllowInlineComments: true},
+	}
+	for name, opts := range cases {
+		c, err := opts.ParseString("[db]\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := c.section("db")
+		for opt, value := range values {
+			s.Add(opt, value)
+		}
+
+		again := roundTrip(t, c, opts)
+		for opt, want := range values {
+			if got := again.section("db").ValueOf(opt); got != want {
+				t.Errorf("%s: %s is %q after round trip, want %q", name, opt, got, want)
+			}
+		}
+	}
+}
+
+func TestEscapeValueDelimsWritesEscapes(t *testing.T) {
+	c, err := ParseOptions{EscapeValueDelims: true}.ParseString("[db]\nconnstr = host\\=a;user\\=b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.section("db").ValueOf("connstr"); got != "host=a;user=b" {
+		t.Fatalf("connstr is %q, want %q", got, "host=a;user=b")
+	}
+	if text := c.String(); !strings.Contains(text, `connstr=host\=a;user\=b`) {
+		t.Errorf("written configuration\n%s\ndoes not escape the delimiters of connstr", text)
+	}
+}
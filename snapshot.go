@@ -0,0 +1,174 @@
+package goini
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Snapshot is an immutable view of the values of a configuration, safe to read
+// from any number of goroutines without locking. Values are resolved when the
+// snapshot is taken: inherited options, DEFAULT values, environment overrides and
+// expansion are applied as configured at that time. Names match regardless of
+// case if the configuration ignored case, see IniFile.SetIgnoreCase.
+type Snapshot struct {
+	names      []string                     // section names in order
+	values     map[string]map[string]string // resolved values of the first section of each name
+	ignoreCase bool
+}
+
+// Snapshot returns the last published snapshot of the configuration, publishing
+// one first if there is none. Changes made to the configuration are not visible
+// in the snapshot until Publish is called.
+func (c *IniFile) Snapshot() *Snapshot {
+	if snap := c.snapshot.Load(); snap != nil {
+		return snap
+	}
+	snap := c.takeSnapshot()
+	if c.snapshot.CompareAndSwap(nil, snap) {
+		return snap
+	}
+	return c.snapshot.Load()
+}
+
+// Publish takes a new snapshot of the configuration and swaps it in atomically,
+// so that readers calling Snapshot see the changes made since the previous one.
+func (c *IniFile) Publish() *Snapshot {
+	snap := c.takeSnapshot()
+	c.snapshot.Store(snap)
+	return snap
+}
+
+// takeSnapshot resolves the values of all sections into a new snapshot.
+func (c *IniFile) takeSnapshot() *Snapshot {
+	names := c.SectionNames()
+	snap := &Snapshot{names: names, values: make(map[string]map[string]string, len(names)), ignoreCase: c.IgnoresCase()}
+	for _, name := range names {
+		s := c.section(name)
+		if s == nil {
+			continue
+		}
+		options := c.visibleOptions(s)
+		values := make(map[string]string, len(options))
+		for _, opt := range options {
+			if value, ok := s.ValueOk(opt); ok {
+				values[opt] = value
+			}
+		}
+		snap.values[name] = values
+	}
+	return snap
+}
+
+// visibleOptions returns the names of the options a lookup in s can find: its own,
+// those inherited from its parents and those of the DEFAULT section if enabled.
+func (c *IniFile) visibleOptions(s *Section) []string {
+	var options, visited []*Section
+	for section := s; section != nil && !slices.Contains(visited, section); {
+		visited = append(visited, section)
+		options = append(options, section)
+		parent := section.Parent()
+		if parent == "" {
+			break
+		}
+		section = c.section(parent)
+	}
+	if c.UsesDefaults() && s.Name() != DefaultSectionName {
+		if d := c.section(DefaultSectionName); d != nil && !slices.Contains(visited, d) {
+			options = append(options, d)
+		}
+	}
+
+	var names []string
+	for _, section := range options {
+		section.mutex.RLock()
+		for _, opt := range section.orderedOptions {
+			if !slices.Contains(names, opt) {
+				names = append(names, opt)
+			}
+		}
+		section.mutex.RUnlock()
+	}
+	return names
+}
+
+// section returns the resolved values of the section name and whether it exists.
+func (snap *Snapshot) section(name string) (map[string]string, bool) {
+	if values, ok := snap.values[name]; ok || !snap.ignoreCase {
+		return values, ok
+	}
+	for _, n := range snap.names {
+		if strings.EqualFold(n, name) {
+			values, ok := snap.values[n]
+			return values, ok
+		}
+	}
+	return nil, false
+}
+
+// value returns the value of option in the section, whose resolved values are
+// values, and whether it exists.
+func (snap *Snapshot) value(values map[string]string, option string) (string, bool) {
+	if value, ok := values[option]; ok || !snap.ignoreCase {
+		return value, ok
+	}
+	for opt, value := range values {
+		if strings.EqualFold(opt, option) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// SectionNames returns the distinct section names in the order they were added.
+func (snap *Snapshot) SectionNames() []string {
+	return slices.Clone(snap.names)
+}
+
+// HasSection returns true if a section with the fully qualified section name exists.
+func (snap *Snapshot) HasSection(name string) bool {
+	_, ok := snap.section(name)
+	return ok
+}
+
+// HasOption returns true if the option exists in the specified section.
+func (snap *Snapshot) HasOption(section, option string) bool {
+	_, ok := snap.StringValueOk(section, option)
+	return ok
+}
+
+// StringValueOk returns the string value for the specified section and option and
+// whether both of them exist.
+func (snap *Snapshot) StringValueOk(section, option string) (string, bool) {
+	values, _ := snap.section(section)
+	return snap.value(values, option)
+}
+
+// StringValueSafe returns the string value for the specified section and option,
+// or an empty string if either of them does not exist.
+func (snap *Snapshot) StringValueSafe(section, option string) string {
+	value, _ := snap.StringValueOk(section, option)
+	return value
+}
+
+// StringValue returns the string value for the specified section and option.
+// The error wraps ErrSectionNotFound or ErrOptionNotFound if either does not exist.
+func (snap *Snapshot) StringValue(section, option string) (string, error) {
+	values, ok := snap.section(section)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSectionNotFound, section)
+	}
+	value, ok := snap.value(values, option)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrOptionNotFound, option)
+	}
+	return value, nil
+}
+
+// Options returns a copy of the resolved options of the specified section, or nil
+// if it does not exist.
+func (snap *Snapshot) Options(section string) map[string]string {
+	values, _ := snap.section(section)
+	return maps.Clone(values)
+}
@@ -0,0 +1,230 @@
+package goini
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ValueChange describes a change of the value of an option, see IniFile.Subscribe.
+type ValueChange struct {
+	Section  string
+	SubName  string // of the subsection, see IniFile.AddSubsection
+	Option   string
+	OldValue string
+	NewValue string
+	Existed  bool // the option existed before the change
+	Exists   bool // the option exists after the change
+}
+
+// subscriptionBuffer is the capacity of the channels returned by Subscribe.
+const subscriptionBuffer = 16
+
+// optionKey identifies the option of a section.
+func optionKey(section, option string) string {
+	return section + "\x00" + option
+}
+
+// subKey identifies the option of a section subscribed to.
+type subKey struct {
+	section, subName, option string
+}
+
+// subKey returns the key of the option subscribed to, with the section and option
+// names in lower case if the configuration ignores case.
+func (c *IniFile) subKey(section, subName, option string) subKey {
+	if c.IgnoresCase() {
+		section, option = strings.ToLower(section), strings.ToLower(option)
+	}
+	return subKey{section: section, subName: subName, option: option}
+}
+
+// compareSubKeys orders subscription keys by section, subsection and option.
+func compareSubKeys(a, b subKey) int {
+	return cmp.Or(cmp.Compare(a.section, b.section), cmp.Compare(a.subName, b.subName), cmp.Compare(a.option, b.option))
+}
+
+// Subscribe returns a channel receiving the changes of the value of option in the
+// named section, whether made by Set, Add, SetValueFor, Delete and Rename, or by
+// ReloadIfChanged. Values are compared as stored, without inheritance, defaults nor
+// expansion. Names match regardless of case if the configuration ignores case. The
+// channel is buffered, changes are dropped while it is full.
+// Call Unsubscribe to stop receiving changes and close it.
+func (c *IniFile) Subscribe(section, option string) <-chan ValueChange {
+	return c.SubscribeSubsection(section, "", option)
+}
+
+// SubscribeSubsection returns a channel receiving the changes of the value of option
+// in the `[section "subName"]` subsection, see Subscribe.
+func (c *IniFile) SubscribeSubsection(section, subName, option string) <-chan ValueChange {
+	ch := make(chan ValueChange, subscriptionBuffer)
+	key := c.subKey(section, subName, option)
+
+	c.subsMutex.Lock()
+	defer c.subsMutex.Unlock()
+
+	if c.subs == nil {
+		c.subs = make(map[subKey][]chan ValueChange)
+	}
+	c.subs[key] = append(c.subs[key], ch)
+	c.subscribed.Add(1)
+	return ch
+}
+
+// SubscribeChanges returns a channel receiving the changes of the values of all
+// the options of the configuration, made as described by Subscribe, as a single
+// slice per change: one change for Set, the old and new names for Rename, every
+// change of a transaction for Tx.Commit and every change found by ReloadIfChanged.
+// The channel is buffered, changes are dropped while it is full.
+// Call UnsubscribeChanges to stop receiving changes and close it.
+func (c *IniFile) SubscribeChanges() <-chan []ValueChange {
+	ch := make(chan []ValueChange, subscriptionBuffer)
+
+	c.subsMutex.Lock()
+	defer c.subsMutex.Unlock()
+
+	c.batchSubs = append(c.batchSubs, ch)
+	c.subscribed.Add(1)
+	return ch
+}
+
+// UnsubscribeChanges stops sending changes to ch, returned by SubscribeChanges, and closes it.
+func (c *IniFile) UnsubscribeChanges(ch <-chan []ValueChange) {
+	c.subsMutex.Lock()
+	defer c.subsMutex.Unlock()
+
+	for i, sub := range c.batchSubs {
+		if sub == ch {
+			c.batchSubs = slices.Delete(c.batchSubs, i, i+1)
+			c.subscribed.Add(-1)
+			close(sub)
+			return
+		}
+	}
+}
+
+// Unsubscribe stops sending changes to ch, returned by Subscribe, and closes it.
+func (c *IniFile) Unsubscribe(ch <-chan ValueChange) {
+	c.subsMutex.Lock()
+	defer c.subsMutex.Unlock()
+
+	for key, chans := range c.subs {
+		for i, sub := range chans {
+			if sub == ch {
+				c.subs[key] = append(chans[:i], chans[i+1:]...)
+				if len(c.subs[key]) == 0 {
+					delete(c.subs, key)
+				}
+				c.subscribed.Add(-1)
+				close(sub)
+				return
+			}
+		}
+	}
+}
+
+// notify sends every change to the channels subscribed to its option, and changes
+// at once to the channels returned by SubscribeChanges.
+func (c *IniFile) notify(changes []ValueChange) {
+	if c.subscribed.Load() == 0 || len(changes) == 0 {
+		return
+	}
+	keys := make([]subKey, len(changes))
+	for i, change := range changes {
+		keys[i] = c.subKey(change.Section, change.SubName, change.Option)
+	}
+
+	c.subsMutex.Lock()
+	defer c.subsMutex.Unlock()
+
+	for i, change := range changes {
+		for _, ch := range c.subs[keys[i]] {
+			select {
+			case ch <- change:
+			default:
+			}
+		}
+	}
+	for _, ch := range c.batchSubs {
+		select {
+		case ch <- slices.Clone(changes):
+		default:
+		}
+	}
+}
+
+// subscribedValues returns the values of the options subscribed to, nil if there are
+// no subscriptions. The options of the first section of every name and subsection
+// name are included if there are subscriptions to all changes, see SubscribeChanges.
+func (c *IniFile) subscribedValues() map[subKey]ValueChange {
+	if c.subscribed.Load() == 0 {
+		return nil
+	}
+	c.subsMutex.Lock()
+	keys := make([]subKey, 0, len(c.subs))
+	for key := range c.subs {
+		keys = append(keys, key)
+	}
+	all := len(c.batchSubs) > 0
+	c.subsMutex.Unlock()
+
+	values := make(map[subKey]ValueChange, len(keys))
+	for _, key := range keys {
+		change := ValueChange{Section: key.section, SubName: key.subName, Option: key.option}
+		c.mutex.RLock()
+		s := c.subsection(key.section, key.subName)
+		c.mutex.RUnlock()
+		if s != nil {
+			s.mutex.RLock()
+			change.Section, change.Option = s.name, s.key(key.option)
+			change.NewValue, change.Exists = s.options[change.Option]
+			s.mutex.RUnlock()
+		}
+		values[key] = change
+	}
+	if all {
+		for _, s := range c.Sections() {
+			s.mutex.RLock()
+			for _, opt := range s.orderedOptions {
+				key := c.subKey(s.name, s.subName, opt)
+				if _, ok := values[key]; !ok {
+					values[key] = ValueChange{Section: s.name, SubName: s.subName, Option: opt, NewValue: s.options[opt], Exists: true}
+				}
+			}
+			s.mutex.RUnlock()
+		}
+	}
+	return values
+}
+
+// notifyChanges notifies the changes between the subscribed values before, as returned
+// by subscribedValues, and the current ones, ordered by section and option.
+func (c *IniFile) notifyChanges(before map[subKey]ValueChange) {
+	if before == nil {
+		return
+	}
+	after := c.subscribedValues()
+	keys := slices.Collect(maps.Keys(after))
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, compareSubKeys)
+
+	var changes []ValueChange
+	for _, key := range keys {
+		change, old := after[key], before[key]
+		if !change.Exists && old.Exists {
+			change.Section, change.SubName, change.Option = old.Section, old.SubName, old.Option
+		} else if change.Section == "" {
+			change.Section, change.SubName, change.Option = key.section, key.subName, key.option
+		}
+		change.OldValue, change.Existed = old.NewValue, old.Exists
+		if change.OldValue != change.NewValue || change.Existed != change.Exists {
+			changes = append(changes, change)
+		}
+	}
+	c.notify(changes)
+}
@@ -0,0 +1,100 @@
+package goini
+
+import "testing"
+
+const remotesConfig = "[remote \"a\"]\nurl = https://a.example\n[remote \"b\"]\nurl = https://b.example\n"
+
+// subsection returns the `[name "subName"]` section of c, failing the test if it does not exist.
+func subsection(t *testing.T, c *IniFile, name, subName string) *Section {
+	t.Helper()
+	s, err := c.Subsection(name, subName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// receive returns the change waiting on ch, failing the test if there is none.
+func receive(t *testing.T, ch <-chan ValueChange) ValueChange {
+	t.Helper()
+	select {
+	case change := <-ch:
+		return change
+	default:
+		t.Fatal("no change received")
+		return ValueChange{}
+	}
+}
+
+// noChange fails the test if a change is waiting on ch.
+func noChange(t *testing.T, ch <-chan ValueChange) {
+	t.Helper()
+	select {
+	case change := <-ch:
+		t.Errorf("unexpected change %+v", change)
+	default:
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c, err := ParseString("[server]\nport = 8080\n[client]\nport = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := c.Subscribe("server", "port")
+	defer c.Unsubscribe(ch)
+
+	c.section("client").SetValueFor("port", "2")
+	noChange(t, ch)
+
+	c.section("server").SetValueFor("port", "9090")
+	want := ValueChange{Section: "server", Option: "port", OldValue: "8080", NewValue: "9090", Existed: true, Exists: true}
+	if got := receive(t, ch); got != want {
+		t.Errorf("change is %+v, want %+v", got, want)
+	}
+
+	c.section("server").Delete("port")
+	want = ValueChange{Section: "server", Option: "port", OldValue: "9090", Existed: true}
+	if got := receive(t, ch); got != want {
+		t.Errorf("change is %+v, want %+v", got, want)
+	}
+}
+
+func TestSubscribeIgnoreCase(t *testing.T) {
+	c, err := ParseOptions{IgnoreCase: true}.ParseString("[a]\nx = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := c.Subscribe("A", "X")
+	defer c.Unsubscribe(ch)
+
+	c.section("a").SetValueFor("x", "2")
+	if got := receive(t, ch); got.Section != "a" || got.Option != "x" || got.NewValue != "2" {
+		t.Errorf("change is %+v, want x of a set to 2", got)
+	}
+	if err := c.Set("A.X", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, ch); got.NewValue != "3" {
+		t.Errorf("change is %+v, want x set to 3", got)
+	}
+}
+
+func TestSubscribeSubsection(t *testing.T) {
+	c, err := ParseString(remotesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := c.SubscribeSubsection("remote", "b", "url")
+	defer c.Unsubscribe(ch)
+
+	subsection(t, c, "remote", "a").SetValueFor("url", "https://a2.example")
+	noChange(t, ch)
+
+	subsection(t, c, "remote", "b").SetValueFor("url", "https://b2.example")
+	want := ValueChange{Section: "remote", SubName: "b", Option: "url", OldValue: "https://b.example",
+		NewValue: "https://b2.example", Existed: true, Exists: true}
+	if got := receive(t, ch); got != want {
+		t.Errorf("change is %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,252 @@
+package goini
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SectionTree is a node of the hierarchy formed by dotted section names,
+// where "[app.db.primary]" is a child of "app.db", itself a child of "app".
+type SectionTree struct {
+	Name     string         // last element of the dotted name
+	Path     string         // full dotted name, empty for the root
+	Sections []*Section     // sections named Path, empty if only subsections exist
+	Children []*SectionTree // subsections in the order they first appear
+}
+
+// Child returns the direct child node with the specified name, or nil.
+func (t *SectionTree) Child(name string) *SectionTree {
+	for _, child := range t.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// ChildSections returns the sections directly below prefix in the dotted name hierarchy,
+// e.g. "app.db.primary" and "app.db.replica" for "app.db". An empty prefix returns the
+// sections without a dot in their name.
+func (c *IniFile) ChildSections(prefix string) []*Section {
+	c.mutex.RLock()
+	names := append([]string(nil), c.orderedSections...)
+	c.mutex.RUnlock()
+
+	var children []*Section
+	for _, name := range names {
+		rest := name
+		if prefix != "" {
+			if !strings.HasPrefix(name, prefix+".") {
+				continue
+			}
+			rest = name[len(prefix)+1:]
+		}
+		if rest == "" || strings.Contains(rest, ".") {
+			continue
+		}
+		children = append(children, c.SectionsByName(name)...)
+	}
+	return children
+}
+
+// hasSubsections returns true if a section is named below prefix in the dotted name hierarchy.
+func (c *IniFile) hasSubsections(prefix string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, name := range c.orderedSections {
+		if strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SectionTree returns the hierarchy formed by the dotted section names.
+// Intermediate nodes are created for missing parents.
+func (c *IniFile) SectionTree() *SectionTree {
+	c.mutex.RLock()
+	names := append([]string(nil), c.orderedSections...)
+	c.mutex.RUnlock()
+
+	root := &SectionTree{}
+	for _, name := range names {
+		node := root
+		for _, part := range strings.Split(name, ".") {
+			child := node.Child(part)
+			if child == nil {
+				child = &SectionTree{Name: part, Path: subsectionName(node.Path, part)}
+				node.Children = append(node.Children, child)
+			}
+			node = child
+		}
+		node.Sections = c.SectionsByName(name)
+	}
+	return root
+}
+
+// SubConfig is a live view of the sections below a dotted prefix, with the prefix
+// stripped from their names, see IniFile.Sub. It holds no sections of its own:
+// names are resolved against the configuration on each call, so that changes made
+// through the view or to the configuration are visible to both.
+type SubConfig struct {
+	file   *IniFile
+	prefix string
+}
+
+// Sub returns a view of the sections below prefix in the dotted name hierarchy, so
+// that a library can be handed the "[mylib.*]" sections of a larger configuration
+// and find "[mylib.db]" as "db". The "[mylib]" section itself, and "[mylib.global]"
+// if any, are found under the name of the global section.
+func (c *IniFile) Sub(prefix string) *SubConfig {
+	return &SubConfig{file: c, prefix: prefix}
+}
+
+// Prefix returns the prefix of the sections of the view.
+func (v *SubConfig) Prefix() string {
+	return v.prefix
+}
+
+// File returns the configuration of the view.
+func (v *SubConfig) File() *IniFile {
+	return v.file
+}
+
+// fullNames returns the fully qualified names of the sections named name in the view.
+func (v *SubConfig) fullNames(name string) []string {
+	if name == v.file.GlobalSectionName() {
+		return []string{v.prefix, v.prefix + "." + name}
+	}
+	return []string{v.prefix + "." + name}
+}
+
+// strip returns the name in the view of the section with the fully qualified name,
+// and false if the section is not below the prefix.
+func (v *SubConfig) strip(name string) (string, bool) {
+	n := len(v.prefix)
+	if len(name) < n || !v.samePrefix(name[:n]) {
+		return "", false
+	}
+	if len(name) == n {
+		return v.file.GlobalSectionName(), true
+	}
+	if name[n] != '.' || len(name) == n+1 {
+		return "", false
+	}
+	return name[n+1:], true
+}
+
+// samePrefix returns true if s is the prefix, regardless of case if the
+// configuration ignores case.
+func (v *SubConfig) samePrefix(s string) bool {
+	if v.file.IgnoresCase() {
+		return strings.EqualFold(s, v.prefix)
+	}
+	return s == v.prefix
+}
+
+// Section returns the first section matching the name in the view.
+func (v *SubConfig) Section(name string) (*Section, error) {
+	if sections := v.SectionsByName(name); len(sections) > 0 {
+		return sections[0], nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+}
+
+// Subsection returns the first `[name "subName"]` section matching the name in the view.
+func (v *SubConfig) Subsection(name, subName string) (*Section, error) {
+	for _, s := range v.SectionsByName(name) {
+		if s.SubName() == subName {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s \"%s\"", ErrSectionNotFound, name, subName)
+}
+
+// SectionsByName returns all the sections matching the name in the view.
+func (v *SubConfig) SectionsByName(name string) []*Section {
+	var sections []*Section
+	for _, full := range v.fullNames(name) {
+		sections = append(sections, v.file.SectionsByName(full)...)
+	}
+	return sections
+}
+
+// HasSection returns true if a section matching the name in the view exists.
+func (v *SubConfig) HasSection(name string) bool {
+	return len(v.SectionsByName(name)) > 0
+}
+
+// Sections returns the sections of the view in the order they were added.
+func (v *SubConfig) Sections() []*Section {
+	var sections []*Section
+	for _, s := range v.file.Sections() {
+		if _, ok := v.strip(s.Name()); ok {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// SectionNames returns the distinct section names of the view in the order they were added.
+func (v *SubConfig) SectionNames() []string {
+	var names []string
+	for _, full := range v.file.SectionNames() {
+		if name, ok := v.strip(full); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Name returns the name in the view of a section of the configuration, and false
+// if the section is not part of the view.
+func (v *SubConfig) Name(s *Section) (string, bool) {
+	return v.strip(s.Name())
+}
+
+// StringValue returns the string value for the specified section of the view and option.
+// The error wraps ErrSectionNotFound or ErrOptionNotFound if either does not exist.
+func (v *SubConfig) StringValue(section, option string) (string, error) {
+	s, err := v.Section(section)
+	if err != nil {
+		return "", err
+	}
+	return s.value(option)
+}
+
+// Key returns a handle on the option name of the first section matching the name
+// section in the view, see IniFile.Key.
+func (v *SubConfig) Key(section, name string) *Key {
+	return v.file.Key(v.fullNames(section)[0], name)
+}
+
+// AddSection adds a section to the configuration under the prefix of the view.
+func (v *SubConfig) AddSection(name string) *Section {
+	return v.file.AddSection(v.fullNames(name)[0])
+}
+
+// AddSubsection adds a `[name "subName"]` section to the configuration under the
+// prefix of the view.
+func (v *SubConfig) AddSubsection(name, subName string) *Section {
+	return v.file.AddSubsection(v.fullNames(name)[0], subName)
+}
+
+// DeleteSection deletes all the sections matching the name in the view from the configuration.
+func (v *SubConfig) DeleteSection(name string) error {
+	found := false
+	for _, full := range v.fullNames(name) {
+		switch err := v.file.DeleteSection(full); {
+		case err == nil:
+			found = true
+		case !errors.Is(err, ErrSectionNotFound):
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, name)
+	}
+	return nil
+}
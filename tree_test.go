@@ -0,0 +1,96 @@
+package goini
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSub(t *testing.T) {
+	c, err := ParseString("[app]\nname=app\n[mylib]\nlevel=1\n[mylib.db]\nhost=db\n" +
+		"[mylib.mylib]\nnested=1\n[mylibrary]\nother=1\n[mylib.global]\nexplicit=1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := c.Sub("mylib")
+
+	if got, want := sub.SectionNames(), []string{"global", "db", "mylib"}; !slices.Equal(got, want) {
+		t.Errorf("section names are %q, want %q", got, want)
+	}
+	var names []string
+	for _, s := range sub.Sections() {
+		names = append(names, s.Name())
+	}
+	if want := []string{"mylib", "mylib.db", "mylib.mylib", "mylib.global"}; !slices.Equal(names, want) {
+		t.Errorf("sections are %q, want %q", names, want)
+	}
+	if got := len(sub.SectionsByName("global")); got != 2 {
+		t.Errorf("%d global sections, want [mylib] and [mylib.global]", got)
+	}
+	if got, _ := sub.StringValue("global", "level"); got != "1" {
+		t.Errorf("level is %q, want the value of [mylib]", got)
+	}
+	if got, _ := sub.StringValue("mylib", "nested"); got != "1" {
+		t.Errorf("nested is %q, want the value of [mylib.mylib]", got)
+	}
+	if _, err := sub.Section("app"); !errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("[app] is visible in the view, error %v", err)
+	}
+	if name, ok := sub.Name(c.section("mylib.db")); !ok || name != "db" {
+		t.Errorf("name of [mylib.db] in the view is %q, %v", name, ok)
+	}
+	if _, ok := sub.Name(c.section("mylibrary")); ok {
+		t.Error("[mylibrary] is part of the view")
+	}
+}
+
+func TestSubIsLive(t *testing.T) {
+	c, err := ParseString("[mylib.db]\nhost=db\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := c.Sub("mylib")
+
+	db, err := sub.Section("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Add("host", "changed")
+	if got := c.StringValueSafe("mylib.db", "host"); got != "changed" {
+		t.Errorf("change through the view did not reach the configuration: host is %q", got)
+	}
+
+	c.AddSection("mylib.cache").Add("size", "10")
+	if got := sub.Key("cache", "size").MustInt(0); got != 10 {
+		t.Errorf("size is %d, want the value of the section added to the configuration", got)
+	}
+
+	sub.AddSection("log").Add("level", "debug")
+	if got := c.StringValueSafe("mylib.log", "level"); got != "debug" {
+		t.Errorf("level is %q, want the value of the section added through the view", got)
+	}
+
+	if err := sub.DeleteSection("db"); err != nil {
+		t.Fatal(err)
+	}
+	if c.HasSection("mylib.db") {
+		t.Error("[mylib.db] still exists once deleted through the view")
+	}
+	if err := sub.DeleteSection("db"); !errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("deleting a missing section returned %v", err)
+	}
+}
+
+func TestSubIgnoreCase(t *testing.T) {
+	c, err := ParseOptions{IgnoreCase: true}.ParseString("[MyLib.DB]\nhost=db\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := c.Sub("mylib")
+	if got := sub.SectionNames(); !slices.Equal(got, []string{"DB"}) {
+		t.Errorf("section names are %q, want [DB]", got)
+	}
+	if got, _ := sub.StringValue("db", "host"); got != "db" {
+		t.Errorf("host is %q, want db", got)
+	}
+}
@@ -0,0 +1,157 @@
+package goini
+
+import "errors"
+
+// ErrTxDone is returned when using a transaction already committed or rolled back.
+var ErrTxDone = errors.New("transaction already committed or rolled back")
+
+// Tx is a batch of changes to a configuration applied at once by Commit, see
+// IniFile.Begin. A Tx must not be used by several goroutines.
+type Tx struct {
+	file *IniFile
+	ops  []txOp
+	done bool
+}
+
+// txOp is a change recorded by a transaction.
+type txOp struct {
+	kind    txKind
+	section string
+	subName string
+	exact   bool // the section is the one of subName, not the first of that name, see Tx.restore
+	option  string
+	value   string
+}
+
+type txKind int
+
+const (
+	txSet txKind = iota
+	txDelete
+	txAddSection
+	txRename // option renamed to value
+)
+
+// Begin starts a transaction on the configuration. Its changes are only applied
+// when Commit is called.
+func (c *IniFile) Begin() *Tx {
+	return &Tx{file: c}
+}
+
+// Set records setting the value of the option at path, as accepted by IniFile.Set.
+func (tx *Tx) Set(path, value string) *Tx {
+	section, option := tx.file.splitPath(path)
+	tx.ops = append(tx.ops, txOp{kind: txSet, section: section, option: option, value: value})
+	return tx
+}
+
+// Delete records deleting the option at path from the first section of that name.
+// Options that do not exist when committing are ignored.
+func (tx *Tx) Delete(path string) *Tx {
+	section, option := tx.file.splitPath(path)
+	tx.ops = append(tx.ops, txOp{kind: txDelete, section: section, option: option})
+	return tx
+}
+
+// AddSection records adding the section name if it does not exist.
+func (tx *Tx) AddSection(name string) *Tx {
+	tx.ops = append(tx.ops, txOp{kind: txAddSection, section: name})
+	return tx
+}
+
+// Rollback discards the changes of the transaction.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done, tx.ops = true, nil
+	return nil
+}
+
+// Commit applies the changes of the transaction while holding the lock of the
+// configuration, so that lookups through the configuration see either none or all
+// of them. Subscribers are notified once every change is applied, those returned by
+// IniFile.SubscribeChanges receiving all the changes at once, and a new snapshot
+// is published if one was taken, see Snapshot. The changes of the values are returned.
+func (tx *Tx) Commit() ([]ValueChange, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	if tx.file.Frozen() {
+		return nil, ErrFrozen
+	}
+	tx.done = true
+	changes := tx.apply()
+	tx.file.record(edit{changes: changes})
+	return changes, nil
+}
+
+// apply applies the changes of the transaction, notifies the subscribers and returns
+// the changes of the values.
+func (tx *Tx) apply() []ValueChange {
+	c := tx.file
+
+	c.mutex.Lock()
+	var changes []ValueChange
+	for _, op := range tx.ops {
+		var s *Section
+		if op.exact {
+			s = c.subsection(op.section, op.subName)
+		} else if sections := c.sections[c.nameOf(op.section)]; len(sections) > 0 {
+			s = sections[0]
+		}
+		switch op.kind {
+		case txAddSection:
+			if s == nil {
+				c.addSubsection(op.section, op.subName)
+			}
+		case txSet:
+			if s == nil {
+				s = c.addSubsection(op.section, op.subName)
+			}
+			option, old, existed := s.setOption(op.option, op.value)
+			changes = c.txChanged(changes, ValueChange{Section: s.name, SubName: s.subName, Option: option,
+				OldValue: old, NewValue: op.value, Existed: existed, Exists: true})
+		case txDelete:
+			if s == nil {
+				continue
+			}
+			option, old, existed := s.deleteOption(op.option)
+			changes = c.txChanged(changes, ValueChange{Section: s.name, SubName: s.subName, Option: option,
+				OldValue: old, Existed: existed})
+		case txRename:
+			if s == nil {
+				continue
+			}
+			value, err := s.rename(op.option, op.value)
+			if err != nil {
+				continue
+			}
+			changes = c.txChanged(changes, ValueChange{Section: s.name, SubName: s.subName, Option: op.option,
+				OldValue: value, Existed: true})
+			changes = c.txChanged(changes, ValueChange{Section: s.name, SubName: s.subName, Option: op.value,
+				NewValue: value, Exists: true})
+		}
+	}
+	c.mutex.Unlock()
+
+	c.notify(changes)
+	if c.snapshot.Load() != nil {
+		c.Publish()
+	}
+	return changes
+}
+
+// txChanged appends change to changes and records it as a modification if the value
+// changed. The caller must hold the lock.
+func (c *IniFile) txChanged(changes []ValueChange, change ValueChange) []ValueChange {
+	if change.OldValue == change.NewValue && change.Existed == change.Exists {
+		return changes
+	}
+	if c.modified == nil {
+		c.modified = make(map[string]bool)
+	}
+	c.modified[optionKey(change.Section, change.Option)] = true
+	c.version++
+	return append(changes, change)
+}
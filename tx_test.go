@@ -0,0 +1,25 @@
+package goini
+
+import "testing"
+
+func TestTxDeleteMatchesSectionDelete(t *testing.T) {
+	const text = "[Service]\nExecStart=\nExecStart=/bin/daemon\nUser=nobody\n"
+	opts := ParseOptions{Dialect: DialectSystemd}
+	direct, err := opts.ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transacted, err := opts.ParseString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	direct.section("Service").Delete("ExecStart")
+	direct.section("Service").Add("ExecStart", "/bin/other")
+	if _, err := transacted.Begin().Delete("Service.ExecStart").Set("Service.ExecStart", "/bin/other").Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := transacted.String(), direct.String(); got != want {
+		t.Errorf("after the transaction:\n%s\nwant, as with Section.Delete and Section.Add:\n%s", got, want)
+	}
+}
@@ -0,0 +1,408 @@
+package goini
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseBool parses the common boolean spellings used in configuration files.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", value)
+}
+
+// Int returns the value of the specified option as an int.
+func (s *Section) Int(option string) (int, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+// Int64 returns the value of the specified option as an int64.
+func (s *Section) Int64(option string) (int64, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+}
+
+// Uint64 returns the value of the specified option as an uint64.
+func (s *Section) Uint64(option string) (uint64, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+}
+
+// Float64 returns the value of the specified option as a float64.
+func (s *Section) Float64(option string) (float64, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(value), 64)
+}
+
+// Bool returns the value of the specified option as a bool.
+// Accepted values are 1/0, t/f, true/false, y/n, yes/no and on/off, case insensitive.
+func (s *Section) Bool(option string) (bool, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return false, err
+	}
+	return parseBool(value)
+}
+
+// Duration returns the value of the specified option as a time.Duration, see time.ParseDuration.
+func (s *Section) Duration(option string) (time.Duration, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(strings.TrimSpace(value))
+}
+
+// Time returns the value of the specified option as a time.Time parsed with layout, see time.Parse.
+func (s *Section) Time(option string, layout string) (time.Time, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(layout, strings.TrimSpace(value))
+}
+
+// TimeRFC3339 returns the value of the specified option as a time.Time in RFC 3339 format.
+func (s *Section) TimeRFC3339(option string) (time.Time, error) {
+	return s.Time(option, time.RFC3339)
+}
+
+// byteUnits maps lower-cased unit suffixes to their multiplier. Single-letter and
+// "KB"-style suffixes are SI units, "KiB"-style suffixes IEC units.
+var byteUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "ki": 1 << 10, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mi": 1 << 20, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gi": 1 << 30, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "ti": 1 << 40, "tib": 1 << 40,
+	"p": 1e15, "pb": 1e15, "pi": 1 << 50, "pib": 1 << 50,
+	"e": 1e18, "eb": 1e18, "ei": 1 << 60, "eib": 1 << 60,
+}
+
+// parseBytes parses a byte size such as "512", "64KB", "1.5GiB" or "2G".
+func parseBytes(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(value)
+	}
+	multiplier, ok := byteUnits[strings.ToLower(strings.TrimSpace(value[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit in %q", value)
+	}
+	n, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+	size := n * multiplier
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q out of range", value)
+	}
+	return int64(size), nil
+}
+
+// Bytes returns the value of the specified option as a number of bytes. The value may
+// have a SI unit suffix (K, KB, M, MB, ... powers of 1000) or an IEC one (Ki, KiB, Mi,
+// MiB, ... powers of 1024), case insensitive: "64KB", "10MiB" and "2G" are all valid.
+func (s *Section) Bytes(option string) (int64, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return 0, err
+	}
+	return parseBytes(value)
+}
+
+// IP returns the value of the specified option as an IPv4 or IPv6 address.
+func (s *Section) IP(option string) (net.IP, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(strings.TrimSpace(value))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", value)
+	}
+	return ip, nil
+}
+
+// CIDR returns the value of the specified option as a network in CIDR notation, like "10.0.0.0/8".
+func (s *Section) CIDR(option string) (*net.IPNet, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return nil, err
+	}
+	_, network, err := net.ParseCIDR(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// HostPort returns the host and port of the specified option of the form "host:port",
+// "[ipv6]:port" or ":port". The port must be a number between 0 and 65535.
+func (s *Section) HostPort(option string) (host string, port int, err error) {
+	value, err := s.value(option)
+	if err != nil {
+		return "", 0, err
+	}
+	host, p, err := net.SplitHostPort(strings.TrimSpace(value))
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q", value)
+	}
+	return host, int(n), nil
+}
+
+// URL returns the value of the specified option as an absolute URL.
+func (s *Section) URL(option string) (*url.URL, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("missing scheme in URL %q", value)
+	}
+	return u, nil
+}
+
+// MustString returns the value of the specified option or fallback if the option does not exist.
+func (s *Section) MustString(option string, fallback string) string {
+	value, err := s.value(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustInt returns the value of the specified option as an int or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustInt(option string, fallback int) int {
+	value, err := s.Int(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustInt64 returns the value of the specified option as an int64 or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustInt64(option string, fallback int64) int64 {
+	value, err := s.Int64(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustUint64 returns the value of the specified option as an uint64 or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustUint64(option string, fallback uint64) uint64 {
+	value, err := s.Uint64(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustFloat64 returns the value of the specified option as a float64 or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustFloat64(option string, fallback float64) float64 {
+	value, err := s.Float64(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustBool returns the value of the specified option as a bool or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustBool(option string, fallback bool) bool {
+	value, err := s.Bool(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// MustDuration returns the value of the specified option as a time.Duration or fallback
+// if the option does not exist or is malformed.
+func (s *Section) MustDuration(option string, fallback time.Duration) time.Duration {
+	value, err := s.Duration(option)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Strings returns the value of the specified option split on delim, with whitespace
+// trimmed from each element. delim defaults to "," if empty.
+func (s *Section) Strings(option string, delim string) ([]string, error) {
+	value, err := s.value(option)
+	if err != nil {
+		return nil, err
+	}
+	if delim == "" {
+		delim = ","
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, delim)
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts, nil
+}
+
+// Ints returns the value of the specified option as a list of ints, see Strings.
+func (s *Section) Ints(option string, delim string) ([]int, error) {
+	parts, err := s.Strings(option, delim)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		if values[i], err = strconv.Atoi(part); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Int64s returns the value of the specified option as a list of int64s, see Strings.
+func (s *Section) Int64s(option string, delim string) ([]int64, error) {
+	parts, err := s.Strings(option, delim)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, len(parts))
+	for i, part := range parts {
+		if values[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Floats returns the value of the specified option as a list of float64s, see Strings.
+func (s *Section) Floats(option string, delim string) ([]float64, error) {
+	parts, err := s.Strings(option, delim)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		if values[i], err = strconv.ParseFloat(part, 64); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Bools returns the value of the specified option as a list of bools, see Strings and Bool.
+func (s *Section) Bools(option string, delim string) ([]bool, error) {
+	parts, err := s.Strings(option, delim)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]bool, len(parts))
+	for i, part := range parts {
+		if values[i], err = parseBool(part); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Durations returns the value of the specified option as a list of time.Durations, see Strings.
+func (s *Section) Durations(option string, delim string) ([]time.Duration, error) {
+	parts, err := s.Strings(option, delim)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]time.Duration, len(parts))
+	for i, part := range parts {
+		if values[i], err = time.ParseDuration(part); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// isArrayKey returns true if option is a PHP-style "key[]" array key.
+func isArrayKey(option string) bool {
+	return strings.HasSuffix(option, "[]")
+}
+
+// Slice returns the values of the PHP-style array option written as "option[] = value"
+// lines, in order, or nil if there is none. Such lines always append to the array.
+func (s *Section) Slice(option string) []string {
+	return s.ValuesOf(option + "[]")
+}
+
+// Map returns the values of the PHP-style map option written as "option[key] = value"
+// lines, by key, or nil if there is none. Values are resolved as by ValueOf, and
+// keys inherited from the parent or DEFAULT sections are included, see
+// Section.Parent and IniFile.SetUseDefaults. The option name matches regardless
+// of case if the configuration ignores case.
+func (s *Section) Map(option string) map[string]string {
+	options := s.OptionNames()
+	ignoreCase := false
+	if s.file != nil {
+		options, ignoreCase = s.file.visibleOptions(s), s.file.IgnoresCase()
+	}
+
+	var m map[string]string
+	prefix := option + "["
+	for _, opt := range options {
+		if len(opt) < len(prefix)+2 || !strings.HasSuffix(opt, "]") {
+			continue
+		}
+		if head := opt[:len(prefix)]; head != prefix && !(ignoreCase && strings.EqualFold(head, prefix)) {
+			continue
+		}
+		key := opt[len(prefix) : len(opt)-1]
+		if _, ok := m[key]; ok {
+			continue // spelled differently in a section read first
+		}
+		value, err := s.value(opt)
+		if err != nil {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[key] = value
+	}
+	return m
+}
@@ -0,0 +1,48 @@
+package goini
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	t.Setenv("GOINI_TEST_HOST", "expanded")
+	c, err := ParseOptions{ExpandEnv: true}.ParseString("[DEFAULT]\nhosts[default] = d\n" +
+		"[s]\nhosts[a] = ${GOINI_TEST_HOST}\nhosts[b] = b\nhosts[] = array\nhostsx = 1\nother[c] = c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.section("s")
+	if got, want := s.Map("hosts"), map[string]string{"a": "expanded", "b": "b"}; !maps.Equal(got, want) {
+		t.Errorf("map is %v, want %v", got, want)
+	}
+	if got := s.Map("missing"); got != nil {
+		t.Errorf("map of a missing option is %v, want nil", got)
+	}
+
+	c.SetUseDefaults(true)
+	if got, want := s.Map("hosts"), map[string]string{"a": "expanded", "b": "b", "default": "d"}; !maps.Equal(got, want) {
+		t.Errorf("map with defaults is %v, want %v", got, want)
+	}
+
+	t.Setenv("GOINI_TEST_OVERRIDE", "overridden")
+	c.SetEnvOverride("test", func(prefix, section, option string) string {
+		if option == "hosts[b]" {
+			return "GOINI_TEST_OVERRIDE"
+		}
+		return ""
+	})
+	if got := s.Map("hosts")["b"]; got != "overridden" {
+		t.Errorf("hosts[b] is %q, want the environment override", got)
+	}
+}
+
+func TestMapIgnoreCase(t *testing.T) {
+	c, err := ParseOptions{IgnoreCase: true}.ParseString("[s]\nHosts[a] = 1\nhosts[b] = 2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.section("s").Map("HOSTS"), map[string]string{"a": "1", "b": "2"}; !maps.Equal(got, want) {
+		t.Errorf("map is %v, want %v", got, want)
+	}
+}
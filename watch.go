@@ -0,0 +1,110 @@
+package goini
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchDebounce is how long Watch waits for the file to stop changing before parsing
+// it again.
+var WatchDebounce = 100 * time.Millisecond
+
+// fileState identifies a version of a file by its modification time and size.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFile returns the state of the file at filePath.
+func statFile(filePath string) (fileState, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Watch watches the file of the configuration until ctx is done, and calls fn with
+// the configuration parsed again with the same parse options, or the error, once the
+// file changed and no event was received for WatchDebounce. The configuration itself
+// is left unchanged. The directory of the file is watched, so that files replaced by
+// a rename, as editors do when saving, or removed and created again are followed by
+// path. fn is not called when the content of the file is unchanged.
+// Watch blocks until ctx is done and returns its error, ErrNoFilePath, or
+// fsnotify.ErrClosed if the watcher of the directory stops.
+func (c *IniFile) Watch(ctx context.Context, fn func(*IniFile, error)) error {
+	filePath := c.FilePath()
+	if filePath == "" {
+		return ErrNoFilePath
+	}
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return err
+	}
+	c.mutex.RLock()
+	opts, sum := c.parseOptions, c.loadedSum
+	c.mutex.RUnlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(filePath)); err != nil {
+		return err
+	}
+
+	debounce := time.NewTimer(WatchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fsnotify.ErrClosed
+			}
+			fn(nil, err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return fsnotify.ErrClosed
+			}
+			if filepath.Clean(event.Name) == filePath && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				debounce.Reset(WatchDebounce)
+			}
+		case <-debounce.C:
+			data, err := os.ReadFile(filePath)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // being replaced, a Create event follows
+			}
+			if err != nil {
+				fn(nil, err)
+				continue
+			}
+			next := sha256.Sum256(data)
+			if next == sum {
+				continue
+			}
+			sum = next
+			parsed, err := opts.parse(bytes.NewReader(data), filePath, nil)
+			if err != nil {
+				fn(nil, err)
+				continue
+			}
+			parsed.loadedSum = sum
+			if state, err := statFile(filePath); err == nil {
+				parsed.loaded = state
+			}
+			fn(parsed, nil)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package goini
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watchFile starts watching the configuration of filePath and returns the
+// configurations parsed again. Watching stops at the end of the test.
+func watchFile(t *testing.T, filePath string) (*IniFile, <-chan *IniFile) {
+	t.Helper()
+	debounce := WatchDebounce
+	WatchDebounce = 20 * time.Millisecond
+	t.Cleanup(func() { WatchDebounce = debounce })
+
+	c, err := Parse(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	parsed := make(chan *IniFile, 10)
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(ctx, func(next *IniFile, err error) {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			parsed <- next
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Watch returned %v, want context.Canceled", err)
+		}
+	})
+	return c, parsed
+}
+
+// awaitChange calls write until a configuration is parsed again, as the watcher may
+// not be started yet, and returns it.
+func awaitChange(t *testing.T, parsed <-chan *IniFile, write func()) *IniFile {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for write(); ; {
+		select {
+		case next := <-parsed:
+			return next
+		case <-tick.C:
+			write()
+		case <-timeout:
+			t.Fatal("no change reported")
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "watch.ini")
+	writeConfig := func(text string) func() {
+		return func() {
+			if err := os.WriteFile(filePath, []byte(text), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeConfig("[server]\nport = 8080\n")()
+	c, parsed := watchFile(t, filePath)
+
+	next := awaitChange(t, parsed, writeConfig("[server]\nport = 9090\n"))
+	if got := next.StringValueSafe("server", "port"); got != "9090" {
+		t.Errorf("port is %q in the parsed configuration, want 9090", got)
+	}
+	if got := c.StringValueSafe("server", "port"); got != "8080" {
+		t.Errorf("port is %q in the watched configuration, want it unchanged", got)
+	}
+
+	writeConfig("[server]\nport = 9090\n")()
+	select {
+	case next := <-parsed:
+		t.Errorf("unchanged content reported, port %q", next.StringValueSafe("server", "port"))
+	case <-time.After(10 * WatchDebounce):
+	}
+
+	replaced := filepath.Join(filepath.Dir(filePath), "watch.ini.tmp")
+	next = awaitChange(t, parsed, func() {
+		if err := os.WriteFile(replaced, []byte("[server]\nport = 7070\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(replaced, filePath); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := next.StringValueSafe("server", "port"); got != "7070" {
+		t.Errorf("port is %q once the file is replaced, want 7070", got)
+	}
+}
+
+func TestWatchNoFilePath(t *testing.T) {
+	c, err := ParseString("[s]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch(context.Background(), func(*IniFile, error) {}); !errors.Is(err, ErrNoFilePath) {
+		t.Errorf("error is %v, want ErrNoFilePath", err)
+	}
+}